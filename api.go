@@ -5,3 +5,10 @@ import "github.com/moisespsena-go/assetfs/assetfsapi"
 type Interface = assetfsapi.Interface
 type AssetInterface = assetfsapi.AssetInterface
 type Compresseder = assetfsapi.Compresseder
+type Digester = assetfsapi.Digester
+
+// TraversableInterface traversable of the file systems of this package, with the context methods
+type TraversableInterface interface {
+	assetfsapi.TraversableInterface
+	assetfsapi.ContextTraversableInterface
+}
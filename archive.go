@@ -0,0 +1,423 @@
+package assetfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	oscommon "github.com/moisespsena-go/os-common"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+var (
+	ErrUnsupportedArchive = errors.New("unsupported archive format")
+	ErrArchiveTooLarge    = errors.New("uncompressed archive is too large")
+
+	// ArchiveMaxMemorySize is the max uncompressed size of the compressed tar archives, which are loaded into
+	// memory. Larger archives fails with ErrArchiveTooLarge.
+	ArchiveMaxMemorySize int64 = 256 << 20
+)
+
+type archiveNode struct {
+	name     string
+	path     string
+	size     int64
+	mode     os.FileMode
+	modTime  time.Time
+	open     func() (io.ReadCloser, error)
+	children map[string]*archiveNode
+	names    []string
+}
+
+func (n *archiveNode) child(name string, modTime time.Time) *archiveNode {
+	if c, ok := n.children[name]; ok {
+		return c
+	}
+	if n.children == nil {
+		n.children = map[string]*archiveNode{}
+	}
+	c := &archiveNode{name: name, path: joinPath(n.path, name), mode: os.ModeDir | 0555, modTime: modTime}
+	n.children[name] = c
+	n.names = append(n.names, name)
+	return c
+}
+
+// ArchiveLayer read only layer of a zip or tar archive. Supported formats are `.zip`, `.tar`, `.tar.gz` and
+// `.tgz`. Readers of stored zip entries and of tar entries implements io.Seeker and io.ReaderAt. Readers of
+// compressed zip entries seeks using the size of the entry header, and uncompress the entry again on backward
+// seeks; its ReadAt loads the entry contents into memory. Compressed tar archives are uncompressed into memory,
+// up to ArchiveMaxMemorySize bytes. Close closes the archive file; UnregisterLayer calls it.
+type ArchiveLayer struct {
+	Path   string
+	root   *archiveNode
+	closer io.Closer
+}
+
+// OpenArchive opens the archive file pth
+func OpenArchive(pth string) (l *ArchiveLayer, err error) {
+	var f *os.File
+	if f, err = os.Open(pth); err != nil {
+		return
+	}
+	var info os.FileInfo
+	if info, err = f.Stat(); err != nil {
+		f.Close()
+		return
+	}
+	l = &ArchiveLayer{Path: pth, root: &archiveNode{path: ".", mode: os.ModeDir | 0555, modTime: info.ModTime()}}
+	name := strings.ToLower(pth)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		err = l.loadZip(f, info)
+	case strings.HasSuffix(name, ".tar"):
+		if err = l.loadTar(f, f); err == nil {
+			l.closer = f
+		}
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		if err = l.loadTarGz(f); err == nil {
+			f.Close()
+		}
+	default:
+		err = ErrUnsupportedArchive
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("open archive %q: %v", pth, err)
+	}
+	l.sort(l.root)
+	return
+}
+
+func (l *ArchiveLayer) sort(n *archiveNode) {
+	sort.Strings(n.names)
+	for _, c := range n.children {
+		l.sort(c)
+	}
+}
+
+func (l *ArchiveLayer) add(name string, info os.FileInfo, open func() (io.ReadCloser, error)) {
+	name = cleanPath(name)
+	if name == "." {
+		return
+	}
+	n := l.root
+	for _, part := range strings.Split(name, "/") {
+		n = n.child(part, l.root.modTime)
+	}
+	n.mode, n.modTime = info.Mode(), info.ModTime()
+	if !info.IsDir() {
+		n.size, n.open = info.Size(), open
+	}
+}
+
+func (l *ArchiveLayer) loadZip(f *os.File, info os.FileInfo) error {
+	r, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return err
+	}
+	for _, zf := range r.File {
+		zf := zf
+		var open func() (io.ReadCloser, error)
+		if zf.Method == zip.Store {
+			offset, err := zf.DataOffset()
+			if err != nil {
+				return err
+			}
+			size := int64(zf.UncompressedSize64)
+			open = func() (io.ReadCloser, error) {
+				return sectionReadCloser{io.NewSectionReader(f, offset, size)}, nil
+			}
+		} else {
+			open = func() (io.ReadCloser, error) {
+				return newLazySeekReader(zf.Open, int64(zf.UncompressedSize64))
+			}
+		}
+		l.add(zf.Name, zf.FileInfo(), open)
+	}
+	l.closer = f
+	return nil
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (n int, err error) {
+	n, err = c.r.Read(p)
+	c.n += int64(n)
+	return
+}
+
+func (l *ArchiveLayer) loadTar(r io.Reader, ra io.ReaderAt) error {
+	cr := &countReader{r: r}
+	tr := tar.NewReader(cr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			l.add(h.Name, h.FileInfo(), nil)
+		case tar.TypeReg:
+			offset, size := cr.n, h.Size
+			l.add(h.Name, h.FileInfo(), func() (io.ReadCloser, error) {
+				return sectionReadCloser{io.NewSectionReader(ra, offset, size)}, nil
+			})
+		}
+	}
+	return nil
+}
+
+func (l *ArchiveLayer) loadTarGz(f *os.File) error {
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(gz, ArchiveMaxMemorySize+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > ArchiveMaxMemorySize {
+		return ErrArchiveTooLarge
+	}
+	return l.loadTar(bytes.NewReader(data), bytes.NewReader(data))
+}
+
+func (l *ArchiveLayer) get(pth string) *archiveNode {
+	n := l.root
+	if pth = cleanPath(pth); pth == "." {
+		return n
+	}
+	for _, name := range strings.Split(pth, "/") {
+		if n = n.children[name]; n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+func (l *ArchiveLayer) info(n *archiveNode) assetfsapi.FileInfo {
+	info := &ArchiveFileInfo{assetfsapi.NewBasicFileInfo(n.path, n.size, n.mode, n.modTime, time.Time{}), l, n}
+	if n.mode.IsDir() {
+		return &ArchiveDirFileInfo{info}
+	}
+	return info
+}
+
+func (l *ArchiveLayer) AssetInfoC(_ context.Context, pth string) (assetfsapi.FileInfo, error) {
+	if n := l.get(pth); n != nil {
+		return l.info(n), nil
+	}
+	return nil, oscommon.ErrNotFound(pth)
+}
+
+func (l *ArchiveLayer) ReadDir(dir string, cb assetfsapi.CbWalkInfoFunc, skipDir bool) (err error) {
+	n := l.get(dir)
+	if n == nil {
+		return oscommon.ErrNotFound(dir)
+	}
+	if !n.mode.IsDir() {
+		return IS_NOT_DIR_ERROR
+	}
+	for _, name := range n.names {
+		c := n.children[name]
+		if skipDir && c.mode.IsDir() {
+			continue
+		}
+		if err = cb(l.info(c)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Close closes the archive file
+func (l *ArchiveLayer) Close() error {
+	if l.closer != nil {
+		return l.closer.Close()
+	}
+	return nil
+}
+
+func (l *ArchiveLayer) String() string {
+	return l.Path
+}
+
+type ArchiveFileInfo struct {
+	assetfsapi.BasicFileInfo
+	layer *ArchiveLayer
+	node  *archiveNode
+}
+
+func (ArchiveFileInfo) Type() assetfsapi.FileType {
+	return assetfsapi.FileTypeNormal
+}
+
+func (a *ArchiveFileInfo) GetFileInfo() os.FileInfo {
+	return a.BasicFileInfo
+}
+
+func (a *ArchiveFileInfo) RealPath() string {
+	return a.layer.Path + "!/" + a.node.path
+}
+
+func (a *ArchiveFileInfo) Reader() (io.ReadCloser, error) {
+	return a.node.open()
+}
+
+func (a *ArchiveFileInfo) String() string {
+	return StringifyFileInfo(a)
+}
+
+type ArchiveDirFileInfo struct {
+	*ArchiveFileInfo
+}
+
+func (ArchiveDirFileInfo) Type() assetfsapi.FileType {
+	return assetfsapi.FileTypeDir
+}
+
+func (a *ArchiveDirFileInfo) Reader() (io.ReadCloser, error) {
+	return nil, IS_DIR_ERROR
+}
+
+func (a *ArchiveDirFileInfo) ReadDir(cb func(child assetfsapi.FileInfo) error) error {
+	return a.layer.ReadDir(a.node.path, cb, false)
+}
+
+func (a *ArchiveDirFileInfo) String() string {
+	return StringifyFileInfo(a)
+}
+
+type sectionReadCloser struct {
+	*io.SectionReader
+}
+
+func (sectionReadCloser) Close() error {
+	return nil
+}
+
+// lazySeekReader streams the contents from open. Seek only moves the offset, using the known size for
+// io.SeekEnd, and the next Read skips forward, or reopens the contents on backward seeks. ReadAt loads all
+// contents into memory.
+type lazySeekReader struct {
+	open func() (io.ReadCloser, error)
+	rc   io.ReadCloser
+	size int64
+	// pos position of rc
+	pos int64
+	// off offset of next Read
+	off int64
+	r   *bytes.Reader
+}
+
+func newLazySeekReader(open func() (io.ReadCloser, error), size int64) (*lazySeekReader, error) {
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	return &lazySeekReader{open: open, rc: rc, size: size}, nil
+}
+
+func (l *lazySeekReader) Read(p []byte) (n int, err error) {
+	if l.r != nil {
+		return l.r.Read(p)
+	}
+	if l.off >= l.size {
+		return 0, io.EOF
+	}
+	if err = l.skip(); err != nil {
+		return
+	}
+	n, err = l.rc.Read(p)
+	l.pos += int64(n)
+	l.off = l.pos
+	return
+}
+
+// skip moves rc to off
+func (l *lazySeekReader) skip() (err error) {
+	if l.off < l.pos {
+		var rc io.ReadCloser
+		if rc, err = l.open(); err != nil {
+			return
+		}
+		l.rc.Close()
+		l.rc, l.pos = rc, 0
+	}
+	if l.off > l.pos {
+		var n int64
+		n, err = io.CopyN(ioutil.Discard, l.rc, l.off-l.pos)
+		l.pos += n
+	}
+	return
+}
+
+func (l *lazySeekReader) load() (err error) {
+	if l.r != nil {
+		return
+	}
+	var rc io.ReadCloser
+	if rc, err = l.open(); err != nil {
+		return
+	}
+	defer rc.Close()
+	var data []byte
+	if data, err = ioutil.ReadAll(rc); err != nil {
+		return
+	}
+	l.rc.Close()
+	l.r = bytes.NewReader(data)
+	_, err = l.r.Seek(l.off, io.SeekStart)
+	return
+}
+
+func (l *lazySeekReader) Seek(offset int64, whence int) (int64, error) {
+	if l.r != nil {
+		return l.r.Seek(offset, whence)
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += l.off
+	case io.SeekEnd:
+		offset += l.size
+	default:
+		return 0, errors.New("lazySeekReader.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("lazySeekReader.Seek: negative position")
+	}
+	l.off = offset
+	return offset, nil
+}
+
+func (l *lazySeekReader) ReadAt(p []byte, off int64) (int, error) {
+	if err := l.load(); err != nil {
+		return 0, err
+	}
+	return l.r.ReadAt(p, off)
+}
+
+func (l *lazySeekReader) Close() error {
+	if l.r != nil {
+		return nil
+	}
+	return l.rc.Close()
+}
@@ -0,0 +1,78 @@
+package assetfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveLayerSeek(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	pth := filepath.Join(t.TempDir(), "a.zip")
+	f, err := os.Create(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "a.txt", Method: zip.Deflate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	fs := NewAssetFileSystem()
+	if err = fs.RegisterArchive(pth); err != nil {
+		t.Fatal(err)
+	}
+	info, err := fs.AssetInfo("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := info.Reader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	r := rc.(*lazySeekReader)
+
+	if size, err := r.Seek(0, io.SeekEnd); err != nil || size != int64(len(data)) {
+		t.Fatalf("SeekEnd: %d, %v", size, err)
+	}
+	if r.r != nil {
+		t.Fatal("SeekEnd loaded contents into memory")
+	}
+	for _, off := range []int64{5000, 10, 9990} {
+		if _, err = r.Seek(off, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, 10)
+		if _, err = io.ReadFull(r, b); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, data[off:off+10]) {
+			t.Fatalf("offset %d: got %q", off, b)
+		}
+	}
+	if b, err := ioutil.ReadAll(r); err != nil || len(b) != 0 {
+		t.Fatalf("read at end: %q, %v", b, err)
+	}
+
+	layer := fs.Layers()[0].(*ArchiveLayer)
+	if err = fs.UnregisterLayer(layer); err != nil {
+		t.Fatal(err)
+	}
+	if len(fs.Layers()) != 0 {
+		t.Fatal("layer not unregistered")
+	}
+	if _, err = layer.closer.(*os.File).Stat(); err == nil {
+		t.Fatal("archive file not closed")
+	}
+}
@@ -70,6 +70,17 @@ type TraversableInterface interface {
 	NewGlobString(pattern string) Glob
 }
 
+// ContextTraversableInterface is implemented by the traversables with context methods. WalkC, WalkInfoC,
+// ReadDirC, GlobC and GlobInfoC are like the methods without context, but the local sources of ctx
+// contribute entries, and the traversal stops with ctx.Err() when ctx is done.
+type ContextTraversableInterface interface {
+	WalkC(ctx context.Context, dir string, cb CbWalkFunc, mode ...WalkMode) error
+	WalkInfoC(ctx context.Context, dir string, cb CbWalkInfoFunc, mode ...WalkMode) error
+	ReadDirC(ctx context.Context, dir string, cb CbWalkInfoFunc, skipDir bool) (err error)
+	GlobC(ctx context.Context, pattern GlobPattern, cb func(pth string, isDir bool) error) error
+	GlobInfoC(ctx context.Context, pattern GlobPattern, cb func(info FileInfo) error) error
+}
+
 type Plugin interface {
 	Init(fs Interface)
 	PathRegisterCallback(fs Interface)
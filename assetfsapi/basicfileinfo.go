@@ -25,6 +25,8 @@ func NewCleanedBasicFileInfo(pth string, name ...string) BasicFileInfo {
 	b := &basicFileInfo{path: pth}
 	if len(name) == 0 || name[0] == "" {
 		b.name = path.Base(pth)
+	} else {
+		b.name = name[0]
 	}
 	return b
 }
@@ -62,8 +64,10 @@ func (fi *basicFileInfo) ChangeTime() time.Time {
 	return fi.changeTime
 }
 
+// IsDir reports if the mode has the directory bit. File infos of directories must be created with
+// os.ModeDir in mode.
 func (fi basicFileInfo) IsDir() bool {
-	return false
+	return fi.mode.IsDir()
 }
 
 func (fi basicFileInfo) Sys() interface{} {
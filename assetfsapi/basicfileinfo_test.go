@@ -0,0 +1,19 @@
+package assetfsapi
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBasicFileInfoIsDir(t *testing.T) {
+	if NewBasicFileInfo("a.txt", 1, 0644, time.Time{}, time.Time{}).IsDir() {
+		t.Error("file is dir")
+	}
+	if !NewBasicFileInfo("d", 0, os.ModeDir|0755, time.Time{}, time.Time{}).IsDir() {
+		t.Error("dir is not dir")
+	}
+	if NewCleanedBasicFileInfo("ns").IsDir() {
+		t.Error("info without mode is dir")
+	}
+}
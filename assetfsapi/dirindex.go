@@ -0,0 +1,198 @@
+package assetfsapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	oscommon "github.com/moisespsena-go/os-common"
+)
+
+// DirIndexEntry entry of directory listing
+type DirIndexEntry struct {
+	Name      string    `json:"name"`
+	Dir       bool      `json:"dir,omitempty"`
+	NameSpace bool      `json:"namespace,omitempty"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+}
+
+// DirIndex directory listing
+type DirIndex struct {
+	Path    string          `json:"path"`
+	Entries []DirIndexEntry `json:"entries"`
+}
+
+// ReadDirIndex returns the listing of dir: directories and namespaces first, then files, sorted by name
+func ReadDirIndex(fs Interface, dir string) (index *DirIndex, err error) {
+	dir = path.Clean(dir)
+	index = &DirIndex{Path: dir, Entries: []DirIndexEntry{}}
+	if err = fs.ReadDir(dir, func(info FileInfo) error {
+		e := DirIndexEntry{
+			Name:      info.Name(),
+			Dir:       info.IsDir(),
+			NameSpace: info.Type().IsNameSpace(),
+			ModTime:   info.ModTime(),
+		}
+		if !e.Dir {
+			e.Size = info.Size()
+		}
+		index.Entries = append(index.Entries, e)
+		return nil
+	}, false); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(index.Entries, func(i, j int) bool {
+		a, b := index.Entries[i], index.Entries[j]
+		if a.Dir != b.Dir {
+			return a.Dir
+		}
+		return a.Name < b.Name
+	})
+	return
+}
+
+var dirIndexTemplate = template.Must(template.New("dir_index").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Index of /{{.Path}}</title></head>
+<body>
+<h1>Index of /{{.Path}}</h1>
+<table>
+<tr><th>Name</th><th>Size</th><th>Modified</th></tr>
+{{- range .Entries}}
+<tr><td><a href="{{.Name}}{{if .Dir}}/{{end}}">{{.Name}}{{if .Dir}}/{{end}}</a>{{if .NameSpace}} (namespace){{end}}</td><td>{{if not .Dir}}{{.Size}}{{end}}</td><td>{{.ModTime.UTC.Format "2006-01-02 15:04:05"}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// httpError responds with not found status if err is a not exists error, otherwise with internal server
+// error status
+func httpError(w http.ResponseWriter, r *http.Request, err error) {
+	if os.IsNotExist(err) || oscommon.IsNotFound(err) {
+		http.NotFound(w, r)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// ServeDirIndex writes the listing of dir as HTML, or as JSON if the request accepts `application/json`.
+// HEAD requests have only the headers. The listing is rendered before writing, so its errors responds with
+// internal server error status.
+func ServeDirIndex(w http.ResponseWriter, r *http.Request, fs Interface, dir string) {
+	index, err := ReadDirIndex(fs, dir)
+	if err != nil {
+		httpError(w, r, err)
+		return
+	}
+	if index.Path == "." {
+		index.Path = ""
+	}
+	var (
+		buf   bytes.Buffer
+		ctype = "text/html; charset=utf-8"
+	)
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		ctype = "application/json; charset=utf-8"
+		err = json.NewEncoder(&buf).Encode(index)
+	} else {
+		err = dirIndexTemplate.Execute(&buf, index)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Type", ctype)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(buf.Bytes()))
+}
+
+// DirRedirect redirects to the directory URL of the request, with trailing slash, keeping the query
+func DirRedirect(w http.ResponseWriter, r *http.Request) {
+	location := path.Base(r.URL.Path) + "/"
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// ServeHTTP serves the files of FS. Directories are served by its `index.html` file or, if DirIndex, by
+// its listing.
+func (fs *HttpFileSystem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upath := path.Clean("/" + r.URL.Path)
+	name := strings.TrimPrefix(upath, "/")
+	if name == "" {
+		name = "."
+	}
+
+	var isDir = name == "."
+	if !isDir {
+		info, err := fs.FS.AssetInfoC(r.Context(), name)
+		if err != nil {
+			httpError(w, r, err)
+			return
+		}
+		if !info.IsDir() {
+			serveFileInfo(w, r, info)
+			return
+		}
+	}
+
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		DirRedirect(w, r)
+		return
+	}
+
+	index := path.Join(name, "index.html")
+	if info, err := fs.FS.AssetInfoC(r.Context(), index); err == nil && !info.IsDir() {
+		serveFileInfo(w, r, info)
+		return
+	} else if err != nil && !os.IsNotExist(err) && !oscommon.IsNotFound(err) {
+		httpError(w, r, err)
+		return
+	}
+	if fs.DirIndex {
+		ServeDirIndex(w, r, fs.FS, name)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+// serveFileInfo serves info by http.ServeContent. Contents without seeker, as the compressed ones, are read
+// into memory.
+func serveFileInfo(w http.ResponseWriter, r *http.Request, info FileInfo) {
+	rc, err := info.Reader()
+	if err != nil {
+		httpError(w, r, err)
+		return
+	}
+	defer rc.Close()
+
+	var data []byte
+	rs := ReadSeekerOf(rc, info.Size())
+	if c, ok := rc.(Compresseder); ok && c.Compressed() {
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(rc); err == nil {
+			data, err = ioutil.ReadAll(gz)
+		}
+		rs = bytes.NewReader(data)
+	} else if rs == nil {
+		data, err = ioutil.ReadAll(rc)
+		rs = bytes.NewReader(data)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), rs)
+}
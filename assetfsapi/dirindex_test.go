@@ -0,0 +1,55 @@
+package assetfsapi_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/moisespsena-go/assetfs"
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+type errorLayer struct{}
+
+func (errorLayer) AssetInfoC(context.Context, string) (assetfsapi.FileInfo, error) {
+	return nil, errors.New("broken")
+}
+
+func (errorLayer) ReadDir(string, assetfsapi.CbWalkInfoFunc, bool) error {
+	return errors.New("broken")
+}
+
+func TestHttpFileSystem(t *testing.T) {
+	fs := assetfs.NewAssetFileSystem()
+	fs.RegisterLayer(assetfs.NewMapFileSystem(map[string]*assetfs.MapFile{"a.txt": {Data: []byte("abcdef")}}))
+	broken := assetfs.NewAssetFileSystem()
+	broken.RegisterLayer(errorLayer{})
+
+	for _, c := range []struct {
+		fs     assetfsapi.Interface
+		path   string
+		header []string
+		status int
+		body   string
+	}{
+		{fs, "/a.txt", nil, http.StatusOK, "abcdef"},
+		{fs, "/a.txt", []string{"Range", "bytes=1-2"}, http.StatusPartialContent, "bc"},
+		{fs, "/b.txt", nil, http.StatusNotFound, ""},
+		{broken, "/a.txt", nil, http.StatusInternalServerError, ""},
+		{broken, "/", nil, http.StatusInternalServerError, ""},
+	} {
+		r := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.header != nil {
+			r.Header.Set(c.header[0], c.header[1])
+		}
+		w := httptest.NewRecorder()
+		(&assetfsapi.HttpFileSystem{FS: c.fs, DirIndex: true}).ServeHTTP(w, r)
+		if w.Code != c.status {
+			t.Errorf("%s %v: expected status %d, got %d", c.path, c.header, c.status, w.Code)
+		} else if c.body != "" && w.Body.String() != c.body {
+			t.Errorf("%s %v: expected body %q, got %q", c.path, c.header, c.body, w.Body.String())
+		}
+	}
+}
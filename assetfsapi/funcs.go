@@ -15,3 +15,7 @@ type WalkFunc = func(pth string, cb CbWalkFunc, mode WalkMode) error
 type WalkInfoFunc = func(path string, cb CbWalkInfoFunc, mode WalkMode) error
 type GlobFunc = func(pattern GlobPattern, cb func(pth string, isDir bool) error) error
 type GlobInfoFunc = func(pattern GlobPattern, cb func(info FileInfo) error) error
+
+type WalkInfoFuncC = func(ctx context.Context, path string, cb CbWalkInfoFunc, mode WalkMode) error
+type ReadDirFuncC = func(ctx context.Context, dir string, cb CbWalkInfoFunc, skipDir bool) error
+type GlobInfoFuncC = func(ctx context.Context, pattern GlobPattern, cb func(info FileInfo) error) error
@@ -1,5 +1,7 @@
 package assetfsapi
 
+import "context"
+
 type GlobPattern interface {
 	Dir() string
 	Pattern() string
@@ -29,6 +31,15 @@ type Glob interface {
 	InfosOrPanic() []FileInfo
 }
 
+// ContextGlob is implemented by the globs with context methods, like the methods without context but using
+// the context traversal methods of the file system, if it implements ContextTraversableInterface
+type ContextGlob interface {
+	NameC(ctx context.Context, cb func(pth string, isDir bool) error) error
+	NamesC(ctx context.Context) ([]string, error)
+	InfoC(ctx context.Context, cb func(info FileInfo) error) error
+	InfosC(ctx context.Context) ([]FileInfo, error)
+}
+
 type GlobError struct {
 	Err error
 }
@@ -57,6 +57,9 @@ func (fs *HttpFileSystem) Open(name string) (f http.File, err error) {
 		if rsc, ok = r.(iocommon.ReadSeekCloser); ok {
 			return
 		}
+		if rs := ReadSeekerOf(r, asset.Size()); rs != nil {
+			return readSeekCloser{rs, r}, nil
+		}
 		return readCloseUnsupportSeeker{r}, nil
 	})
 	return
@@ -92,6 +95,11 @@ func httpAssetDirReader(fs Interface, path string) func(count int) (items []os.F
 	}
 }
 
+type readSeekCloser struct {
+	io.ReadSeeker
+	io.Closer
+}
+
 type readCloseUnsupportSeeker struct {
 	io.ReadCloser
 }
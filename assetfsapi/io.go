@@ -1,5 +1,30 @@
 package assetfsapi
 
+import (
+	"crypto/sha256"
+	"io"
+)
+
 type Compresseder interface {
 	Compressed() bool
 }
+
+// Digester provides the SHA-256 digest of the uncompressed file contents
+type Digester interface {
+	Digest() [sha256.Size]byte
+}
+
+// ReadSeekerOf returns r if it is an io.ReadSeeker, or a section reader of r with size if it is an
+// io.ReaderAt and size is known. Otherwise returns nil. The returned reader keeps the underlying reader
+// (like *os.File) to allow zero copy by io.ReaderFrom writers.
+func ReadSeekerOf(r io.Reader, size int64) io.ReadSeeker {
+	switch t := r.(type) {
+	case io.ReadSeeker:
+		return t
+	case io.ReaderAt:
+		if size >= 0 {
+			return io.NewSectionReader(t, 0, size)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package assetfsapi
+
+import "context"
+
+// Layer is a source of files stacked by the overlay of a LayerRegistrator. Paths are relative to the layer
+// root. Any Interface is a Layer.
+type Layer interface {
+	AssetInfoC(ctx context.Context, path string) (FileInfo, error)
+	ReadDir(dir string, cb CbWalkInfoFunc, skipDir bool) error
+}
+
+// LayerRegistrator is a PathRegistrator which stacks layers
+type LayerRegistrator interface {
+	PathRegistrator
+	PrependLayer(layer Layer) error
+	RegisterLayer(layer Layer) error
+	// UnregisterLayer removes layer. If layer is an io.Closer, it is closed.
+	UnregisterLayer(layer Layer) error
+}
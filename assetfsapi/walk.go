@@ -7,6 +7,7 @@ const (
 	WalkNameSpacesLookUp
 	WalkParentLookUp
 	WalkReverse
+	WalkSorted
 
 	WalkAll = WalkFiles | WalkDirs | WalkNameSpaces | WalkNameSpacesLookUp | WalkParentLookUp
 )
@@ -35,3 +36,9 @@ func (f WalkMode) IsParentLookUp() bool {
 func (f WalkMode) IsReverse() bool {
 	return (f & WalkReverse) != 0
 }
+
+// IsSorted returns if the entries of each directory are walked sorted by name. With WalkReverse, the order
+// is descending.
+func (f WalkMode) IsSorted() bool {
+	return (f & WalkSorted) != 0
+}
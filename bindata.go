@@ -0,0 +1,546 @@
+package assetfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	oscommon "github.com/moisespsena-go/os-common"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+	"github.com/moisespsena-go/assetfs/local"
+)
+
+// BindataFile file stored into BindataFileSystem
+type BindataFile struct {
+	Path    string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+	Digest  [sha256.Size]byte
+	// Data the file contents. If Compressed, Data is gzip compressed.
+	Data       string
+	Compressed bool
+}
+
+type bindataNode struct {
+	BindataFile
+	name     string
+	dir      bool
+	ns       *BindataFileSystem
+	children map[string]*bindataNode
+	names    []string
+}
+
+func (n *bindataNode) child(name string) *bindataNode {
+	if n.children == nil {
+		return nil
+	}
+	return n.children[name]
+}
+
+func (n *bindataNode) addChild(child *bindataNode) {
+	if n.children == nil {
+		n.children = map[string]*bindataNode{}
+	}
+	if _, ok := n.children[child.name]; !ok {
+		i := sort.SearchStrings(n.names, child.name)
+		n.names = append(n.names, "")
+		copy(n.names[i+1:], n.names[i:])
+		n.names[i] = child.name
+	}
+	n.children[child.name] = child
+}
+
+func (n *bindataNode) each(cb func(child *bindataNode) error) (err error) {
+	for _, name := range n.names {
+		if err = cb(n.children[name]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// BindataFileSystem AssetFS with files stored into the binary
+type BindataFileSystem struct {
+	assetfsapi.AssetGetterInterface
+	TraversableInterface
+	local.LocalSourcesAttribute
+
+	parent    *BindataFileSystem
+	root      *bindataNode
+	path      string
+	nameSpace string
+	handler   http.Handler
+	plugins   []assetfsapi.Plugin
+}
+
+func NewBindataFileSystem() *BindataFileSystem {
+	fs := &BindataFileSystem{root: &bindataNode{dir: true}}
+	fs.root.ns = fs
+	fs.root.Mode = os.ModeDir | 0755
+	fs.init()
+	return fs
+}
+
+func (fs *BindataFileSystem) init() {
+	fs.AssetGetterInterface = &AssetGetter{
+		fs: fs,
+		AssetFunc: func(ctx context.Context, name string) (data []byte, err error) {
+			var info assetfsapi.FileInfo
+			if info, err = fs.assetInfo(ctx, name); err != nil {
+				return
+			}
+			return Data(info)
+		},
+		AssetInfoFunc: fs.assetInfo,
+	}
+	fs.TraversableInterface = &Traversable{
+		FS: fs,
+		WalkFunc: func(dir string, cb assetfsapi.CbWalkFunc, mode assetfsapi.WalkMode) error {
+			return fs.walk(dir, func(info assetfsapi.FileInfo) error {
+				return cb(info.Path(), info.IsDir())
+			}, mode)
+		},
+		WalkInfoFunc: fs.walk,
+		ReadDirFunc:  fs.readDir,
+		GlobFunc: func(pattern assetfsapi.GlobPattern, cb func(pth string, isDir bool) error) error {
+			return globInfo(context.Background(), fs, pattern, func(info assetfsapi.FileInfo) error {
+				return cb(info.Path(), info.IsDir())
+			})
+		},
+		GlobInfoFunc: func(pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
+			return globInfo(context.Background(), fs, pattern, cb)
+		},
+		GlobInfoFuncC: func(ctx context.Context, pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
+			return globInfo(ctx, fs, pattern, cb)
+		},
+	}
+}
+
+// Add add file to file system. Parent directories are created if not exists.
+func (fs *BindataFileSystem) Add(file BindataFile) {
+	file.Path = cleanPath(file.Path)
+	if file.Mode&os.ModeType == 0 && file.Mode.Perm() == 0 {
+		file.Mode |= 0644
+	}
+	dir, node := fs.root, &bindataNode{BindataFile: file, name: path.Base(file.Path)}
+	if parent := path.Dir(file.Path); parent != "." {
+		dir = fs.mkdirAll(parent, file.ModTime)
+	}
+	dir.addChild(node)
+}
+
+// AddData add file with contents data
+func (fs *BindataFileSystem) AddData(pth string, data []byte, mode os.FileMode, modTime time.Time) {
+	fs.Add(BindataFile{
+		Path:    pth,
+		Size:    int64(len(data)),
+		Mode:    mode,
+		ModTime: modTime,
+		Digest:  sha256.Sum256(data),
+		Data:    string(data),
+	})
+}
+
+func (fs *BindataFileSystem) mkdirAll(pth string, modTime time.Time) (node *bindataNode) {
+	node = fs.root
+	for _, name := range strings.Split(pth, "/") {
+		child := node.child(name)
+		if child == nil {
+			child = &bindataNode{
+				BindataFile: BindataFile{Path: path.Join(node.Path, name), Mode: os.ModeDir | 0755},
+				name:        name,
+				dir:         true,
+			}
+			node.addChild(child)
+		}
+		if child.ModTime.Before(modTime) {
+			child.ModTime = modTime
+		}
+		node = child
+	}
+	return
+}
+
+func (fs *BindataFileSystem) get(pth string) *bindataNode {
+	node := fs.root
+	if pth = cleanPath(pth); pth == "." {
+		return node
+	}
+	for _, name := range strings.Split(pth, "/") {
+		if node = node.child(name); node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+func (fs *BindataFileSystem) info(pth string, node *bindataNode) assetfsapi.FileInfo {
+	basic := assetfsapi.NewBasicFileInfo(pth, node.Size, node.Mode, node.ModTime, time.Time{})
+	if node.ns != nil && node != fs.root {
+		return &NameSpaceFileInfo{basic, node.ns}
+	}
+	info := &BindataFileInfo{basic, node}
+	if node.dir {
+		return &BindataDirFileInfo{info, fs}
+	}
+	return info
+}
+
+func (fs *BindataFileSystem) assetInfo(ctx context.Context, pth string) (assetfsapi.FileInfo, error) {
+	pth = cleanPath(pth)
+	for _, src := range local.AllSources(fs.LocalSources(), ctx) {
+		if info, err := src.Get(path.Join(fs.path, pth)); err == nil && !info.IsDir() {
+			return &RealFileInfo{basicFileInfo(pth, info), info.Path()}, nil
+		}
+	}
+	if node := fs.get(pth); node != nil {
+		return fs.info(pth, node), nil
+	}
+	return nil, oscommon.ErrNotFound(pth)
+}
+
+func (fs *BindataFileSystem) readDir(dir string, cb assetfsapi.CbWalkInfoFunc, skipDir bool) (err error) {
+	dir = cleanPath(dir)
+	node := fs.get(dir)
+	if node == nil {
+		return oscommon.ErrNotFound(dir)
+	}
+	if !node.dir {
+		return IS_NOT_DIR_ERROR
+	}
+	return node.each(func(child *bindataNode) error {
+		if skipDir && child.dir {
+			return nil
+		}
+		return cb(fs.info(joinPath(dir, child.name), child))
+	})
+}
+
+func (fs *BindataFileSystem) walk(dir string, cb assetfsapi.CbWalkInfoFunc, mode assetfsapi.WalkMode) (err error) {
+	dir = cleanPath(dir)
+	node := fs.get(dir)
+	if node == nil || !node.dir {
+		return nil
+	}
+	var walk func(dir string, node *bindataNode) error
+	walk = func(dir string, node *bindataNode) error {
+		names := node.names
+		if mode.IsReverse() {
+			names = make([]string, len(node.names))
+			for i, name := range node.names {
+				names[len(names)-i-1] = name
+			}
+		}
+		for _, name := range names {
+			child := node.children[name]
+			pth := joinPath(dir, name)
+			if !child.dir {
+				if mode.IsFiles() {
+					if err := cb(fs.info(pth, child)); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if child.ns != nil && !mode.IsNameSpaces() {
+				continue
+			}
+			if mode.IsDirs() {
+				if err := cb(fs.info(pth, child)); err != nil {
+					if err == filepath.SkipDir {
+						continue
+					}
+					return err
+				}
+			}
+			if err := walk(pth, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(dir, node)
+}
+
+func (fs *BindataFileSystem) GetNameSpace(nameSpace string) (assetfsapi.NameSpacedInterface, error) {
+	node := fs.get(nameSpace)
+	if node == nil || node.ns == nil || node == fs.root {
+		return nil, os.ErrNotExist
+	}
+	return node.ns, nil
+}
+
+func (fs *BindataFileSystem) NameSpaces() (items []assetfsapi.NameSpacedInterface) {
+	fs.root.each(func(child *bindataNode) error {
+		if child.ns != nil {
+			items = append(items, child.ns)
+		}
+		return nil
+	})
+	return
+}
+
+// NameSpace return namespaced filesystem
+func (fs *BindataFileSystem) NameSpace(nameSpace string) assetfsapi.NameSpacedInterface {
+	return fs.NameSpaceFS(nameSpace)
+}
+
+// NameSpaceFS return namespaced filesystem
+func (fs *BindataFileSystem) NameSpaceFS(nameSpace string) *BindataFileSystem {
+	for _, name := range strings.Split(strings.Trim(nameSpace, "/"), "/") {
+		node := fs.mkdirAll(name, time.Time{})
+		if node.ns == nil {
+			node.ns = &BindataFileSystem{
+				parent:    fs,
+				root:      node,
+				path:      path.Join(fs.path, name),
+				nameSpace: name,
+				plugins:   fs.plugins,
+			}
+			node.ns.SetLocalSources(fs.LocalSources())
+			node.ns.init()
+		}
+		fs = node.ns
+	}
+	return fs
+}
+
+func (fs *BindataFileSystem) GetName() string {
+	return fs.nameSpace
+}
+
+func (fs *BindataFileSystem) GetPath() string {
+	return fs.path
+}
+
+func (fs *BindataFileSystem) GetParent() assetfsapi.Interface {
+	if fs.parent == nil {
+		return nil
+	}
+	return fs.parent
+}
+
+// Compile does nothing: BindataFileSystem is already compiled
+func (fs *BindataFileSystem) Compile() error {
+	return nil
+}
+
+func (fs *BindataFileSystem) RegisterPlugin(plugins ...assetfsapi.Plugin) {
+	for _, p := range plugins {
+		p.Init(fs)
+	}
+	fs.plugins = append(fs.plugins, plugins...)
+}
+
+func (fs *BindataFileSystem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if fs.handler == nil {
+		fs.handler = HttpStaticHandler(fs)
+	}
+	fs.handler.ServeHTTP(w, r)
+}
+
+func (fs *BindataFileSystem) DumpFiles(cb func(info assetfsapi.FileInfo) error) error {
+	return fs.Dump(cb)
+}
+
+func (fs *BindataFileSystem) Dump(cb func(info assetfsapi.FileInfo) error, ignore ...func(pth string) bool) error {
+	return fs.walk(".", func(info assetfsapi.FileInfo) error {
+		for _, ignore := range ignore {
+			if ignore(info.Path()) {
+				return nil
+			}
+		}
+		return cb(info)
+	}, assetfsapi.WalkFiles|assetfsapi.WalkNameSpaces)
+}
+
+// Files returns all stored files sorted by path
+func (fs *BindataFileSystem) Files() (files []BindataFile) {
+	fs.walk(".", func(info assetfsapi.FileInfo) error {
+		files = append(files, info.(*BindataFileInfo).node.BindataFile)
+		return nil
+	}, assetfsapi.WalkFiles|assetfsapi.WalkNameSpaces)
+	return
+}
+
+// NameSpacesPaths returns the path of all namespaces, recursively
+func (fs *BindataFileSystem) NameSpacesPaths() (names []string) {
+	fs.walk(".", func(info assetfsapi.FileInfo) error {
+		if info.Type().IsNameSpace() {
+			names = append(names, info.Path())
+		}
+		return nil
+	}, assetfsapi.WalkDirs|assetfsapi.WalkNameSpaces)
+	return
+}
+
+type BindataFileInfo struct {
+	assetfsapi.BasicFileInfo
+	node *bindataNode
+}
+
+func (BindataFileInfo) Type() assetfsapi.FileType {
+	return assetfsapi.FileTypeBindata | assetfsapi.FileTypeNormal
+}
+
+func (b *BindataFileInfo) GetFileInfo() os.FileInfo {
+	return b.BasicFileInfo
+}
+
+func (b *BindataFileInfo) RealPath() string {
+	return "bindata:" + b.node.Path
+}
+
+func (b *BindataFileInfo) Digest() [sha256.Size]byte {
+	return b.node.Digest
+}
+
+func (b *BindataFileInfo) Compressed() bool {
+	return b.node.Compressed
+}
+
+func (b *BindataFileInfo) Reader() (io.ReadCloser, error) {
+	r := bindataReader{strings.NewReader(b.node.Data)}
+	if b.node.Compressed {
+		return bindataCompressedReader{r}, nil
+	}
+	return r, nil
+}
+
+func (b *BindataFileInfo) String() string {
+	return StringifyFileInfo(b)
+}
+
+type BindataDirFileInfo struct {
+	*BindataFileInfo
+	fs *BindataFileSystem
+}
+
+func (BindataDirFileInfo) Type() assetfsapi.FileType {
+	return assetfsapi.FileTypeBindata | assetfsapi.FileTypeDir
+}
+
+func (BindataDirFileInfo) IsDir() bool {
+	return true
+}
+
+func (d *BindataDirFileInfo) Reader() (io.ReadCloser, error) {
+	return nil, IS_DIR_ERROR
+}
+
+func (d *BindataDirFileInfo) ReadDir(cb func(child assetfsapi.FileInfo) error) error {
+	return d.fs.readDir(d.Path(), cb, false)
+}
+
+func (d *BindataDirFileInfo) String() string {
+	return StringifyFileInfo(d)
+}
+
+type bindataReader struct {
+	*strings.Reader
+}
+
+func (bindataReader) Close() error {
+	return nil
+}
+
+type bindataCompressedReader struct {
+	bindataReader
+}
+
+func (bindataCompressedReader) Compressed() bool {
+	return true
+}
+
+// compressBindata gzip data and returns it only if smaller than data.
+func compressBindata(data []byte) (compressed []byte, ok bool) {
+	var b bytes.Buffer
+	w, _ := gzip.NewWriterLevel(&b, gzip.BestCompression)
+	if _, err := w.Write(data); err != nil {
+		return nil, false
+	}
+	if err := w.Close(); err != nil || b.Len() >= len(data) {
+		return nil, false
+	}
+	return b.Bytes(), true
+}
+
+func gzipDigest(data []byte) (digest [sha256.Size]byte, err error) {
+	var r *gzip.Reader
+	if r, err = gzip.NewReader(bytes.NewReader(data)); err != nil {
+		return
+	}
+	h := sha256.New()
+	if _, err = io.Copy(h, r); err != nil {
+		return
+	}
+	copy(digest[:], h.Sum(nil))
+	return
+}
+
+// CompileBindata builds a BindataFileSystem with all files and namespaces of fs, resolved by overlay priority.
+// If compress, files are stored gzip compressed when it reduces its size.
+func CompileBindata(ctx context.Context, fs *AssetFileSystem, compress bool) (b *BindataFileSystem, err error) {
+	b = NewBindataFileSystem()
+	var nameSpaces func(prefix string, fs *AssetFileSystem)
+	nameSpaces = func(prefix string, fs *AssetFileSystem) {
+		fs.load().nameSpaces.Each(func(name string, ns *AssetFileSystem) error {
+			b.NameSpaceFS(path.Join(prefix, name))
+			nameSpaces(path.Join(prefix, name), ns)
+			return nil
+		})
+	}
+	nameSpaces("", fs)
+
+	err = fs.DumpFilesC(ctx, func(info assetfsapi.FileInfo) (err error) {
+		var (
+			r    io.ReadCloser
+			data []byte
+			file = BindataFile{
+				Path:    filepath.ToSlash(info.Path()),
+				Size:    info.Size(),
+				Mode:    info.Mode(),
+				ModTime: info.ModTime(),
+			}
+		)
+		if r, err = info.Reader(); err != nil {
+			return
+		}
+		defer r.Close()
+		if data, err = ioutil.ReadAll(r); err != nil {
+			return
+		}
+		if c, ok := r.(Compresseder); ok && c.Compressed() {
+			file.Compressed = true
+			if file.Digest, err = gzipDigest(data); err != nil {
+				return
+			}
+		} else {
+			file.Digest = sha256.Sum256(data)
+			if compress {
+				if c, ok := compressBindata(data); ok {
+					data, file.Compressed = c, true
+				}
+			}
+		}
+		file.Data = string(data)
+		b.Add(file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
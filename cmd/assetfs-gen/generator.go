@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/moisespsena-go/assetfs"
+)
+
+type generator struct {
+	pkg  string
+	name string
+	// prefix prefix of the unexported identifiers and imports of the generated file
+	prefix string
+	// varName name of the exported variable with the file system. If empty, it is not declared.
+	varName string
+	modTime time.Time
+}
+
+func (g *generator) write(w io.Writer, b *assetfs.BindataFileSystem) (err error) {
+	var (
+		buf   bytes.Buffer
+		files = b.Files()
+		blobs = map[string]bool{}
+	)
+
+	p := g.prefix
+	fmt.Fprintf(&buf, `// Code generated by assetfs-gen. DO NOT EDIT.
+
+package %s
+
+import (
+	%sHex "encoding/hex"
+	%sTime "time"
+
+	"github.com/moisespsena-go/assetfs"
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+`, g.pkg, p, p)
+	if g.varName != "" {
+		fmt.Fprintf(&buf, "// %s the compiled file system, registered as %q.\nvar %s assetfsapi.Interface\n\n", g.varName, g.name, g.varName)
+	} else {
+		buf.WriteString("var _ assetfsapi.Interface\n\n")
+	}
+	buf.WriteString("func init() {\n\tfs := assetfs.NewBindataFileSystem()\n")
+
+	for _, ns := range b.NameSpacesPaths() {
+		fmt.Fprintf(&buf, "\tfs.NameSpaceFS(%q)\n", ns)
+	}
+
+	fmt.Fprintf(&buf, "\tfor _, f := range %sFiles {\n\t\tfs.Add(f)\n\t}\n", p)
+	if g.varName != "" {
+		fmt.Fprintf(&buf, "\t%s = fs\n", g.varName)
+	}
+	fmt.Fprintf(&buf, "\tassetfs.RegisterFileSystem(%q, fs)\n}\n\n", g.name)
+
+	fmt.Fprintf(&buf, `func %sDigest(s string) (d [32]byte) {
+	if _, err := %sHex.Decode(d[:], []byte(s)); err != nil {
+		panic(err)
+	}
+	return
+}
+
+var %sFiles = []assetfs.BindataFile{
+`, p, p, p)
+
+	for _, f := range files {
+		key := blobKey(f)
+		modTime := f.ModTime
+		if !g.modTime.IsZero() {
+			modTime = g.modTime
+		}
+		fmt.Fprintf(&buf, "\t{Path: %q, Size: %d, Mode: %#o, ModTime: %sTime.Unix(%d, %d), Digest: %sDigest(%q), Data: %sBlobs[%q], Compressed: %v},\n",
+			f.Path, f.Size, uint32(f.Mode), p, modTime.Unix(), modTime.Nanosecond(), p, hex.EncodeToString(f.Digest[:]), p, key, f.Compressed)
+	}
+
+	fmt.Fprintf(&buf, "}\n\n// %sBlobs stored file contents by its SHA-256 digest\nvar %sBlobs = map[string]string{\n", p, p)
+	for _, f := range files {
+		key := blobKey(f)
+		if blobs[key] {
+			continue
+		}
+		blobs[key] = true
+		fmt.Fprintf(&buf, "\t%q: %s,\n", key, strconv.Quote(f.Data))
+	}
+	buf.WriteString("}\n")
+
+	var src []byte
+	if src, err = format.Source(buf.Bytes()); err != nil {
+		return
+	}
+	_, err = w.Write(src)
+	return
+}
+
+// blobKey returns the key of stored contents of f: the SHA-256 digest of the stored data, so compressed
+// contents of the same file produced by different compressions does not collide.
+func blobKey(f assetfs.BindataFile) string {
+	if !f.Compressed {
+		return hex.EncodeToString(f.Digest[:])
+	}
+	sum := sha256.Sum256([]byte(f.Data))
+	return hex.EncodeToString(sum[:])
+}
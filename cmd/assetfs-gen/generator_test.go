@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/moisespsena-go/assetfs"
+)
+
+const generatorTestMain = `package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"gentest/assets"
+
+	"github.com/moisespsena-go/assetfs"
+)
+
+func main() {
+	data := map[string]string{}
+	for _, pth := range os.Args[1:] {
+		info, err := assets.FS.AssetInfo(pth)
+		if err != nil {
+			panic(err)
+		}
+		if data[pth], err = assetfs.DataS(info); err != nil {
+			panic(err)
+		}
+	}
+	json.NewEncoder(os.Stdout).Encode(data)
+}
+`
+
+// TestGenerator generates a package into a temporary module, builds it and checks the contents of the
+// compiled files.
+func TestGenerator(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// big.txt is stored compressed
+	big := t.TempDir()
+	if err = ioutil.WriteFile(filepath.Join(big, "big.txt"), bytes.Repeat([]byte("assetfs "), 1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := assetfs.NewAssetFileSystem()
+	if err = fs.RegisterPath(big); err != nil {
+		t.Fatal(err)
+	}
+	if err = fs.NameSpaceFS("z").RegisterPath(filepath.Join(root, "t/ns")); err != nil {
+		t.Fatal(err)
+	}
+	for _, pth := range []string{"t/data", "t/data2"} {
+		if err = fs.RegisterPath(filepath.Join(root, pth)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := assetfs.CompileBindata(nil, fs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err = os.Mkdir(filepath.Join(dir, "assets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var compressed bool
+	for _, f := range b.Files() {
+		compressed = compressed || f.Compressed
+	}
+	if !compressed {
+		t.Fatal("no compressed files")
+	}
+
+	var src bytes.Buffer
+	g := &generator{pkg: "assets", name: "gentest", prefix: "assetfs", varName: "FS"}
+	if err = g.write(&src, b); err != nil {
+		t.Fatal(err)
+	}
+	goSum, err := ioutil.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod": "module gentest\n\ngo 1.22\n\nrequire github.com/moisespsena-go/assetfs v0.0.0\n\n" +
+			"replace github.com/moisespsena-go/assetfs => " + root + "\n",
+		"go.sum":           string(goSum),
+		"main.go":          generatorTestMain,
+		"assets/assets.go": src.String(),
+	}
+	for name, data := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]string{
+		"a.txt":            "t/data/a.txt",
+		"y.txt":            "t/data2/y.txt",
+		"z/b/c.txt":        "t/data/z/b/c.txt",
+		"z/a/x.txt":        "t/data2/z/a/x.txt",
+		"z/sub-ns/nsf.txt": "t/ns/sub-ns/nsf.txt",
+		"big.txt":          filepath.Join(big, "big.txt"),
+	}
+	args := []string{"run", "-mod=mod", "."}
+	for pth := range expected {
+		args = append(args, pth)
+	}
+	cmd := exec.Command(gobin, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod")
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			t.Fatalf("%v: %s", err, ee.Stderr)
+		}
+		t.Fatal(err)
+	}
+
+	var data map[string]string
+	if err = json.Unmarshal(out, &data); err != nil {
+		t.Fatal(err)
+	}
+	for pth, real := range expected {
+		if !filepath.IsAbs(real) {
+			real = filepath.Join(root, real)
+		}
+		b, err := ioutil.ReadFile(real)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data[pth] != string(b) {
+			t.Errorf("%s: expected %q, got %q", pth, b, data[pth])
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Command assetfs-gen writes Go source with the files of an AssetFileSystem layout compiled into
+// an assetfs.BindataFileSystem. The generated package registers the file system on init using
+// assetfs.RegisterFileSystem.
+//
+// Usage:
+//
+//	//go:generate go run github.com/moisespsena-go/assetfs/cmd/assetfs-gen -pkg assets -o assets_bindata.go -path ../t/data -path ../t/data2 -path z=../t/ns
+//
+// Each -path is registered in the given order, optionally into a namespace using NAMESPACE=DIR.
+//
+// The unexported identifiers and imports of the generated file starts with -prefix, so they do not collide
+// with the identifiers of the package. The file system is available from the exported variable -var.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/moisespsena-go/assetfs"
+)
+
+type pathsFlag []string
+
+func (p *pathsFlag) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pathsFlag) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+func main() {
+	var (
+		paths   pathsFlag
+		out     = flag.String("o", "assetfs_bindata.go", "output file")
+		pkg     = flag.String("pkg", "", "package name. Default is the name of output directory")
+		name    = flag.String("name", "", "name used to register the file system. Default is package name")
+		gz      = flag.Bool("gzip", false, "store files gzip compressed when it reduces its size")
+		modTime = flag.Int64("modtime", 0, "if not zero, use this unix time as modification time of all files")
+		prefix  = flag.String("prefix", "assetfs", "prefix of unexported identifiers of generated file")
+		varName = flag.String("var", "FS", "name of exported variable with the file system. If empty, it is not declared")
+	)
+	flag.Var(&paths, "path", "`[NAMESPACE=]DIR` to register, in priority order. Can be repeated")
+	flag.Parse()
+
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "assetfs-gen: no paths")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *pkg == "" {
+		abs, err := filepath.Abs(*out)
+		if err != nil {
+			fatal(err)
+		}
+		*pkg = filepath.Base(filepath.Dir(abs))
+	}
+	if *name == "" {
+		*name = *pkg
+	}
+
+	fs := assetfs.NewAssetFileSystem()
+	for _, pth := range paths {
+		var target = fs
+		if i := strings.IndexByte(pth, '='); i >= 0 {
+			target, pth = fs.NameSpaceFS(pth[0:i]), pth[i+1:]
+		}
+		if err := target.RegisterPath(pth); err != nil {
+			fatal(fmt.Errorf("register path %q: %v", pth, err))
+		}
+	}
+
+	b, err := assetfs.CompileBindata(nil, fs, *gz)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *prefix == "" {
+		fatal(fmt.Errorf("empty prefix"))
+	}
+
+	g := &generator{pkg: *pkg, name: *name, prefix: *prefix, varName: *varName}
+	if *modTime != 0 {
+		g.modTime = time.Unix(*modTime, 0)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fatal(err)
+	}
+	if err = g.write(f, b); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(*out)
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "assetfs-gen:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,228 @@
+package assetfs
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+var (
+	// DefaultCompressCacheSize default max size of compressed data of StaticHandler cache
+	DefaultCompressCacheSize int64 = 32 << 20
+	// DefaultCompressMinSize default min file size to compress on the fly
+	DefaultCompressMinSize int64 = 256
+	// CompressibleTypes default media types, or prefixes of media types, compressed on the fly
+	CompressibleTypes = []string{
+		"text/",
+		"application/javascript",
+		"application/x-javascript",
+		"application/json",
+		"application/manifest+json",
+		"application/xml",
+		"application/wasm",
+		"image/svg+xml",
+		"image/x-icon",
+		"font/otf",
+		"font/ttf",
+	}
+)
+
+// isCompressible returns if file name with size can be compressed on the fly
+func (this *StaticHandler) isCompressible(name string, size int64) bool {
+	if this.CompressCache == nil || size < this.CompressMinSize {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(mime.TypeByExtension(path.Ext(name)))
+	if mediaType == "" {
+		return false
+	}
+	types := this.CompressTypes
+	if types == nil {
+		types = CompressibleTypes
+	}
+	for _, t := range types {
+		if strings.HasPrefix(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressEncoding returns the preferred encoding accepted by client with registered encoder
+func (this *StaticHandler) compressEncoding(r *http.Request) string {
+	encodings := this.Encodings
+	if encodings == nil {
+		encodings = ContentEncodings
+	}
+	for _, enc := range encodings {
+		if GetContentEncoder(enc.Name) != nil && acceptsEncoding(r, enc.Name) {
+			return enc.Name
+		}
+	}
+	return ""
+}
+
+type compressCacheEntry struct {
+	key     string
+	modTime time.Time
+	size    int64
+	etag    string
+	data    []byte
+}
+
+// CompressCache LRU cache of contents compressed on the fly, bounded by the total size of compressed data.
+// Entries are keyed by encoding and path, and replaced when the modification time, size or entity tag of
+// the file changes.
+//
+// Concurrent Get calls of the same file, entity tag and encoding compress the contents once.
+type CompressCache struct {
+	MaxSize int64
+
+	mu    sync.Mutex
+	size  int64
+	ll    *list.List
+	items map[string]*list.Element
+	// calls compressions in progress
+	calls map[string]*compressCall
+}
+
+// compressCall compression in progress of Get
+type compressCall struct {
+	wg   sync.WaitGroup
+	data []byte
+	err  error
+}
+
+func NewCompressCache(maxSize int64) *CompressCache {
+	return &CompressCache{MaxSize: maxSize, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+// Get returns the contents of info compressed with encoding, reading it from content if not cached. If the
+// same contents are being compressed by other call, waits for it and returns its result, without reading
+// content.
+func (c *CompressCache) Get(encoding string, info assetfsapi.FileInfo, etag string, content io.Reader) (data []byte, err error) {
+	key := fmt.Sprint(encoding, ":", info.RealPath(), ":", info.Path())
+	if data = c.get(key, info, etag); data != nil {
+		return
+	}
+
+	callKey := key + ":" + etag
+	c.mu.Lock()
+	if call, ok := c.calls[callKey]; ok {
+		c.mu.Unlock()
+		call.wg.Wait()
+		return call.data, call.err
+	}
+	call := &compressCall{}
+	call.wg.Add(1)
+	if c.calls == nil {
+		c.calls = map[string]*compressCall{}
+	}
+	c.calls[callKey] = call
+	c.mu.Unlock()
+
+	defer func() {
+		call.data, call.err = data, err
+		c.mu.Lock()
+		delete(c.calls, callKey)
+		c.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	if data, err = compress(encoding, content); err == nil {
+		c.add(&compressCacheEntry{key, info.ModTime(), info.Size(), etag, data})
+	}
+	return
+}
+
+// compress returns content encoded with encoding
+func compress(encoding string, content io.Reader) (data []byte, err error) {
+	encoder := GetContentEncoder(encoding)
+	if encoder == nil {
+		return nil, fmt.Errorf("content encoder %q not registered", encoding)
+	}
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+	if w, err = encoder(&buf); err != nil {
+		return
+	}
+	if _, err = io.Copy(w, content); err != nil {
+		return
+	}
+	if err = w.Close(); err != nil {
+		return
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *CompressCache) get(key string, info assetfsapi.FileInfo, etag string) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*compressCacheEntry)
+	if !e.modTime.Equal(info.ModTime()) || e.size != info.Size() || e.etag != etag {
+		c.remove(el)
+		return nil
+	}
+	c.ll.MoveToFront(el)
+	return e.data
+}
+
+func (c *CompressCache) add(e *compressCacheEntry) {
+	if int64(len(e.data)) > c.MaxSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[e.key]; ok {
+		c.remove(el)
+	}
+	c.items[e.key] = c.ll.PushFront(e)
+	c.size += int64(len(e.data))
+	for c.size > c.MaxSize {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *CompressCache) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*compressCacheEntry)
+	delete(c.items, e.key)
+	c.size -= int64(len(e.data))
+}
+
+// Len returns the number of cached entries
+func (c *CompressCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Size returns the total size of cached data
+func (c *CompressCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Purge removes all entries
+func (c *CompressCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = map[string]*list.Element{}
+	c.size = 0
+}
@@ -0,0 +1,146 @@
+package assetfs
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ContentEncoding a content coding and the file extension of its precompressed variants
+type ContentEncoding struct {
+	Name string
+	Ext  string
+}
+
+// ContentEncodings default precompressed variants looked up by StaticHandler, in server preference order
+var ContentEncodings = []ContentEncoding{
+	{"br", ".br"},
+	{"zstd", ".zst"},
+	{"gzip", ".gz"},
+}
+
+// ContentDecoder returns a reader of decoded contents of r
+type ContentDecoder func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	contentDecoders = map[string]ContentDecoder{
+		"br": func(r io.Reader) (io.ReadCloser, error) {
+			return ioutil.NopCloser(brotli.NewReader(r)), nil
+		},
+		"zstd": func(r io.Reader) (io.ReadCloser, error) {
+			d, err := zstd.NewReader(r)
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		},
+		"gzip": func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	}
+	contentDecodersMu sync.RWMutex
+)
+
+// RegisterContentDecoder register the decoder of content coding name, used by StaticHandler to serve a
+// precompressed variant to clients that does not accept it. The `br`, `zstd` and `gzip` decoders are
+// registered by default.
+func RegisterContentDecoder(name string, decoder ContentDecoder) {
+	contentDecodersMu.Lock()
+	defer contentDecodersMu.Unlock()
+	contentDecoders[name] = decoder
+}
+
+// GetContentDecoder returns the decoder of content coding name, or nil if not registered
+func GetContentDecoder(name string) ContentDecoder {
+	contentDecodersMu.RLock()
+	defer contentDecodersMu.RUnlock()
+	return contentDecoders[name]
+}
+
+// acceptsEncoding returns if the Accept-Encoding header of r accepts the content coding. An explicit entry of
+// coding takes precedence over `*`.
+func acceptsEncoding(r *http.Request, coding string) bool {
+	var wildcard *bool
+	for _, v := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(v, ";")
+		name := strings.TrimSpace(parts[0])
+		if name != coding && name != "*" {
+			continue
+		}
+		accepts := true
+		for _, param := range parts[1:] {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q == 0 {
+					accepts = false
+				}
+			}
+		}
+		if name == coding {
+			return accepts
+		}
+		wildcard = &accepts
+	}
+	return wildcard != nil && *wildcard
+}
+
+// addVary adds value to Vary header if not added
+func addVary(h http.Header, value string) {
+	for _, v := range h.Values("Vary") {
+		for _, v := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
+
+// ContentEncoder returns a writer that encodes to w
+type ContentEncoder func(w io.Writer) (io.WriteCloser, error)
+
+var (
+	// contentEncoders encoders of on the fly compression, at default levels since they runs on the request
+	// path. Brotli is slow at good levels, so it is used only by precompressed `.br` variants, unless
+	// registered with BrotliContentEncoder.
+	contentEncoders = map[string]ContentEncoder{
+		"zstd": func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		},
+		"gzip": func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriterLevel(w, gzip.DefaultCompression)
+		},
+	}
+	contentEncodersMu sync.RWMutex
+)
+
+// BrotliContentEncoder returns a brotli encoder with level, from brotli.BestSpeed to brotli.BestCompression.
+// Use it to compress on the fly with brotli:
+//
+//	RegisterContentEncoder("br", BrotliContentEncoder(brotli.DefaultCompression))
+func BrotliContentEncoder(level int) ContentEncoder {
+	return func(w io.Writer) (io.WriteCloser, error) {
+		return brotli.NewWriterLevel(w, level), nil
+	}
+}
+
+// RegisterContentEncoder register the encoder of content coding name, used by StaticHandler to compress
+// files on the fly. The `zstd` and `gzip` encoders are registered by default.
+func RegisterContentEncoder(name string, encoder ContentEncoder) {
+	contentEncodersMu.Lock()
+	defer contentEncodersMu.Unlock()
+	contentEncoders[name] = encoder
+}
+
+// GetContentEncoder returns the encoder of content coding name, or nil if not registered
+func GetContentEncoder(name string) ContentEncoder {
+	contentEncodersMu.RLock()
+	defer contentEncodersMu.RUnlock()
+	return contentEncoders[name]
+}
@@ -0,0 +1,87 @@
+package assetfs
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAcceptsEncoding(t *testing.T) {
+	for _, c := range []struct {
+		header  string
+		coding  string
+		accepts bool
+	}{
+		{"", "gzip", false},
+		{"gzip", "gzip", true},
+		{"br, gzip;q=0.5", "gzip", true},
+		{"gzip;q=0", "gzip", false},
+		{"*", "gzip", true},
+		{"*;q=0", "gzip", false},
+		{"gzip;q=0, *", "gzip", false},
+		{"*, gzip;q=0", "gzip", false},
+		{"*;q=0, gzip", "gzip", true},
+		{"br", "gzip", false},
+	} {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", c.header)
+		if accepts := acceptsEncoding(r, c.coding); accepts != c.accepts {
+			t.Errorf("%q accepts %q: expected %v, got %v", c.header, c.coding, c.accepts, accepts)
+		}
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// TestCompressCacheConcurrentGet must be run with `go test -race`
+func TestCompressCacheConcurrentGet(t *testing.T) {
+	var calls int32
+	RegisterContentEncoder("test-slow", func(w io.Writer) (io.WriteCloser, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return nopWriteCloser{w}, nil
+	})
+	defer func() {
+		contentEncodersMu.Lock()
+		delete(contentEncoders, "test-slow")
+		contentEncodersMu.Unlock()
+	}()
+
+	fs := newTestFS()
+	info, err := fs.AssetInfo("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := MustDataS(info)
+
+	c := NewCompressCache(DefaultCompressCacheSize)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := c.Get("test-slow", info, `"etag"`, strings.NewReader(expected))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if string(data) != expected {
+				t.Errorf("expected %q, got %q", expected, data)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("expected 1 compression, got %d", calls)
+	}
+}
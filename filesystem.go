@@ -8,10 +8,11 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
-	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/moisespsena-go/assetfs/local"
 
@@ -21,53 +22,112 @@ import (
 	"github.com/moisespsena/orderedmap"
 )
 
+// assetFileSystemNameSpaces namespaces in registration order. The nil value is empty. It is part of
+// fileSystemState, so it is not changed after created: With returns a changed copy.
 type assetFileSystemNameSpaces struct {
 	*orderedmap.OrderedMap
 }
 
 func (a *assetFileSystemNameSpaces) Get(key string) (*AssetFileSystem, bool) {
+	if a == nil {
+		return nil, false
+	}
 	if v, ok := a.OrderedMap.Get(key); ok {
 		return v.(*AssetFileSystem), true
 	}
 	return nil, false
 }
 
+// Names returns the namespace names in registration order
+func (a *assetFileSystemNameSpaces) Names() []string {
+	if a == nil {
+		return nil
+	}
+	return a.Keys()
+}
+
+// Each calls cb with each namespace in registration order
+func (a *assetFileSystemNameSpaces) Each(cb func(name string, ns *AssetFileSystem) error) (err error) {
+	for _, name := range a.Names() {
+		ns, _ := a.Get(name)
+		if err = cb(name, ns); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// With returns a copy with namespace ns
+func (a *assetFileSystemNameSpaces) With(name string, ns *AssetFileSystem) *assetFileSystemNameSpaces {
+	cp := &assetFileSystemNameSpaces{orderedmap.New()}
+	a.Each(func(name string, ns *AssetFileSystem) error {
+		cp.Set(name, ns)
+		return nil
+	})
+	cp.Set(name, ns)
+	return cp
+}
+
 // AssetFileSystem AssetFS based on FileSystem
 type AssetFileSystem struct {
 	assetfsapi.AssetGetterInterface
-	assetfsapi.TraversableInterface
+	TraversableInterface
 	local.LocalSourcesAttribute
 
-	parent        assetfsapi.Interface
-	paths         []string
-	path          string
-	nameSpaces    map[string]*AssetFileSystem
-	nameSpace     string
-	callbacks     []assetfsapi.PathRegisterCallback
-	handler       http.Handler
-	plugins       []assetfsapi.Plugin
-	pathsFromFunc func(ctx context.Context, dir string, cb func(pth string) error) (err error)
-	localSources  assetfsapi.LocalSourceRegister
+	parent       assetfsapi.Interface
+	path         string
+	nameSpace    string
+	handler      http.Handler
+	handlerOnce  sync.Once
+	localSources assetfsapi.LocalSourceRegister
+
+	// state holds the current *fileSystemState. mu serializes the writers.
+	state atomic.Value
+	mu    sync.Mutex
+	// txMu serializes the commits of transactions into the writable layer
+	txMu sync.Mutex
+}
+
+// fileSystemState registered layers, namespaces, plugins and callbacks of AssetFileSystem. It is immutable:
+// writers changes a copy and stores it, so lookups never lock and always see a consistent registry.
+type fileSystemState struct {
+	layers     []assetfsapi.Layer
+	nameSpaces *assetFileSystemNameSpaces
+	callbacks  []assetfsapi.PathRegisterCallback
+	plugins    []assetfsapi.Plugin
+	// nameSpaceCallbacks callbacks of namespace creation, inherited by namespaces
+	nameSpaceCallbacks []func(ns *AssetFileSystem)
+	compiled           *BindataFileSystem
+	// writable layer which receives the writes
+	writable *DirLayer
+	// writableSource name of local source which receives the writes of contexts which uses it
+	writableSource string
+}
+
+// load returns the current state
+func (fs *AssetFileSystem) load() *fileSystemState {
+	return fs.state.Load().(*fileSystemState)
+}
+
+// update calls f with a copy of current state and stores the copy if f returns true. Slices and maps of the
+// copy are shared with the current state, so f must not change them in place.
+func (fs *AssetFileSystem) update(f func(s *fileSystemState) bool) (s *fileSystemState) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	cp := *fs.load()
+	if !f(&cp) {
+		return nil
+	}
+	fs.state.Store(&cp)
+	return &cp
 }
 
+// RawFileSystem file system of a single registered layer
 type RawFileSystem struct {
 	*AssetFileSystem
 }
 
-func (r *RawFileSystem) rawPathsFrom(ctx context.Context, pth string, cb func(pth string) error) error {
-	pth = filepath.FromSlash(path.Join(r.path, pth))
-	_, err := os.Stat(pth)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
-	return cb(pth)
-}
-
 func (r *RawFileSystem) init() {
-	r.pathsFromFunc = r.rawPathsFrom
 	r.AssetFileSystem.init()
 }
 
@@ -78,6 +138,9 @@ func NewAssetFileSystem() *AssetFileSystem {
 }
 
 func (fs *AssetFileSystem) init() {
+	if fs.state.Load() == nil {
+		fs.state.Store(&fileSystemState{})
+	}
 	fs.AssetGetterInterface = &AssetGetter{
 		fs: fs,
 		AssetFunc: func(ctx context.Context, name string) (data []byte, err error) {
@@ -102,24 +165,51 @@ func (fs *AssetFileSystem) init() {
 	fs.TraversableInterface = &Traversable{
 		FS: fs,
 		WalkFunc: func(dir string, cb assetfsapi.CbWalkFunc, mode assetfsapi.WalkMode) error {
-			return filesystemWalk(fs, dir, func(info assetfsapi.FileInfo) error {
+			return filesystemWalk(context.Background(), fs, dir, func(info assetfsapi.FileInfo) error {
 				return cb(info.Path(), info.IsDir())
 			}, mode)
 		},
 		WalkInfoFunc: func(dir string, cb assetfsapi.CbWalkInfoFunc, mode assetfsapi.WalkMode) error {
-			return filesystemWalk(fs, dir, cb, mode)
+			return filesystemWalk(context.Background(), fs, dir, cb, mode)
 		},
 		GlobFunc: func(pattern assetfsapi.GlobPattern, cb func(pth string, isDir bool) error) (err error) {
-			return filesystemGlob(fs, pattern, cb)
+			return filesystemGlob(context.Background(), fs, pattern, cb)
 		},
 		GlobInfoFunc: func(pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) (err error) {
-			return filesystemGlobInfo(fs, pattern, cb)
+			return filesystemGlobInfo(context.Background(), fs, pattern, cb)
+		},
+		ReadDirFunc: func(dir string, cb assetfsapi.CbWalkInfoFunc, skipDir bool) error {
+			return fs.readDir(context.Background(), dir, cb, false, skipDir)
+		},
+		WalkInfoFuncC: func(ctx context.Context, dir string, cb assetfsapi.CbWalkInfoFunc, mode assetfsapi.WalkMode) error {
+			return filesystemWalk(ctx, fs, dir, cb, mode)
+		},
+		ReadDirFuncC: func(ctx context.Context, dir string, cb assetfsapi.CbWalkInfoFunc, skipDir bool) error {
+			return fs.readDir(ctx, dir, cb, false, skipDir)
+		},
+		GlobInfoFuncC: func(ctx context.Context, pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
+			return filesystemGlobInfo(ctx, fs, pattern, cb)
 		},
 	}
 }
 
 func (fs *AssetFileSystem) OnPathRegister(cb ...assetfsapi.PathRegisterCallback) {
-	fs.callbacks = append(fs.callbacks, cb...)
+	fs.update(func(s *fileSystemState) bool {
+		s.callbacks = append(s.callbacks[:len(s.callbacks):len(s.callbacks)], cb...)
+		return true
+	})
+}
+
+// OnNameSpace adds callbacks called after creation of each namespace of fs or of its namespaces
+func (fs *AssetFileSystem) OnNameSpace(cb ...func(ns *AssetFileSystem)) {
+	s := fs.update(func(s *fileSystemState) bool {
+		s.nameSpaceCallbacks = append(s.nameSpaceCallbacks[:len(s.nameSpaceCallbacks):len(s.nameSpaceCallbacks)], cb...)
+		return true
+	})
+	s.nameSpaces.Each(func(_ string, ns *AssetFileSystem) error {
+		ns.OnNameSpace(cb...)
+		return nil
+	})
 }
 
 func (fs *AssetFileSystem) GetPath() string {
@@ -148,6 +238,77 @@ func (fs *AssetFileSystem) PrependPathFS(pth string, ignoreExists ...bool) (asse
 	return fs.registerPath(pth, true, ignoreExists...)
 }
 
+// RegisterLayer register layer with lower priority than already registered layers
+func (fs *AssetFileSystem) RegisterLayer(layer assetfsapi.Layer) error {
+	fs.registerLayer(layer, false)
+	return nil
+}
+
+// PrependLayer register layer with higher priority than already registered layers
+func (fs *AssetFileSystem) PrependLayer(layer assetfsapi.Layer) error {
+	fs.registerLayer(layer, true)
+	return nil
+}
+
+// UnregisterLayer removes layer from registered layers. If layer is an io.Closer, it is closed, so the
+// resources of archive layers are released.
+func (fs *AssetFileSystem) UnregisterLayer(layer assetfsapi.Layer) error {
+	var removed assetfsapi.Layer
+	fs.update(func(s *fileSystemState) bool {
+		for i, l := range s.layers {
+			if l != layer {
+				dl, ok := l.(*DirLayer)
+				if !ok {
+					continue
+				}
+				if dl2, ok := layer.(*DirLayer); !ok || dl.Dir != dl2.Dir {
+					continue
+				}
+			}
+			removed = l
+			s.layers = append(s.layers[:i:i], s.layers[i+1:]...)
+			return true
+		}
+		return false
+	})
+	if removed == nil {
+		return os.ErrNotExist
+	}
+	if c, ok := removed.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// RegisterArchive register the zip or tar archive file pth as read only layer
+func (fs *AssetFileSystem) RegisterArchive(pth string) error {
+	return fs.registerArchive(pth, false)
+}
+
+// PrependArchive prepend the zip or tar archive file pth as read only layer
+func (fs *AssetFileSystem) PrependArchive(pth string) error {
+	return fs.registerArchive(pth, true)
+}
+
+// RegisterArchiveNameSpace register the zip or tar archive file pth as read only layer of namespace
+func (fs *AssetFileSystem) RegisterArchiveNameSpace(nameSpace, pth string) error {
+	return fs.NameSpaceFS(nameSpace).registerArchive(pth, false)
+}
+
+func (fs *AssetFileSystem) registerArchive(pth string, prepend bool) error {
+	layer, err := OpenArchive(pth)
+	if err != nil {
+		return err
+	}
+	fs.registerLayer(layer, prepend)
+	return nil
+}
+
+// Layers returns the registered layers, from high to low priority
+func (fs *AssetFileSystem) Layers() []assetfsapi.Layer {
+	return append([]assetfsapi.Layer{}, fs.load().layers...)
+}
+
 // RegisterPath register view paths
 func (fs *AssetFileSystem) registerPath(pth string, prepend bool, ignoreExists ...bool) (assetfsapi.Interface, error) {
 	var onlyExists = true
@@ -158,41 +319,67 @@ func (fs *AssetFileSystem) registerPath(pth string, prepend bool, ignoreExists .
 		}
 	}
 	pth = filepath.Clean(pth)
-	var pfs assetfsapi.Interface
 	if _, err := os.Stat(pth); !onlyExists || !os.IsNotExist(err) {
-		var existing bool
-		for _, p := range fs.paths {
-			if p == pth {
-				existing = true
-				break
+		return fs.registerLayer(NewDirLayer(pth), prepend), nil
+	}
+	return nil, errors.New("not found")
+}
+
+// registerLayer register layer if not registered and returns the raw file system of it. Directory layers
+// of same directory are the same layer.
+func (fs *AssetFileSystem) registerLayer(layer assetfsapi.Layer, prepend bool) (pfs assetfsapi.Interface) {
+	s := fs.update(func(s *fileSystemState) bool {
+		for _, l := range s.layers {
+			if l == layer {
+				return false
 			}
-		}
-		if !existing {
-			if prepend {
-				fs.paths = append([]string{pth}, fs.paths...)
-			} else {
-				fs.paths = append(fs.paths, pth)
+			if dl, ok := l.(*DirLayer); ok {
+				if dl2, ok := layer.(*DirLayer); ok && dl.Dir == dl2.Dir {
+					return false
+				}
 			}
+		}
+		if prepend {
+			s.layers = append([]assetfsapi.Layer{layer}, s.layers...)
+		} else {
+			s.layers = append(s.layers[:len(s.layers):len(s.layers)], layer)
+		}
+		return true
+	})
+	if s == nil {
+		return nil
+	}
 
-			pfs = fs.newRawFS(pth)
+	pfs = fs.newRawFS(layer)
 
-			for _, plugin := range fs.plugins {
-				plugin.PathRegisterCallback(pfs)
-			}
+	for _, plugin := range s.plugins {
+		plugin.PathRegisterCallback(pfs)
+	}
 
-			for _, cb := range fs.callbacks {
-				cb(pfs)
-			}
-		}
+	for _, cb := range s.callbacks {
+		cb(pfs)
+	}
+	return
+}
 
-		return pfs, nil
+// Compile compile assetfs into a BindataFileSystem with all registered files, available from Compiled.
+// Lookups of fs are not changed: it keeps serving the files of its layers. To serve the compiled files,
+// use Compiled instead of fs, or generate a package with assetfs-gen.
+func (fs *AssetFileSystem) Compile() (err error) {
+	var b *BindataFileSystem
+	if b, err = CompileBindata(nil, fs, false); err != nil {
+		return
 	}
-	return nil, errors.New("not found")
+	fs.update(func(s *fileSystemState) bool {
+		s.compiled = b
+		return true
+	})
+	return
 }
 
-// Compile compile assetfs
-func (fs *AssetFileSystem) Compile() error {
-	return nil
+// Compiled returns the BindataFileSystem built by last Compile call
+func (fs *AssetFileSystem) Compiled() *BindataFileSystem {
+	return fs.load().compiled
 }
 
 func (fs *AssetFileSystem) GetNameSpace(nameSpace string) (assetfsapi.NameSpacedInterface, error) {
@@ -201,9 +388,7 @@ func (fs *AssetFileSystem) GetNameSpace(nameSpace string) (assetfsapi.NameSpaced
 		ok bool
 	)
 	for _, name := range strings.Split(strings.Trim(nameSpace, "/"), "/") {
-		if fs.nameSpaces == nil {
-			return nil, os.ErrNotExist
-		} else if ns, ok = fs.nameSpaces[name]; !ok {
+		if ns, ok = fs.load().nameSpaces.Get(name); !ok {
 			return nil, os.ErrNotExist
 		}
 		fs = ns
@@ -212,9 +397,10 @@ func (fs *AssetFileSystem) GetNameSpace(nameSpace string) (assetfsapi.NameSpaced
 }
 
 func (fs *AssetFileSystem) NameSpaces() (items []assetfsapi.NameSpacedInterface) {
-	for _, v := range fs.nameSpaces {
-		items = append(items, v)
-	}
+	fs.load().nameSpaces.Each(func(_ string, ns *AssetFileSystem) error {
+		items = append(items, ns)
+		return nil
+	})
 	return
 }
 
@@ -225,37 +411,38 @@ func (fs *AssetFileSystem) NameSpace(nameSpace string) assetfsapi.NameSpacedInte
 
 // NameSpace return namespaced filesystem
 func (fs *AssetFileSystem) NameSpaceFS(nameSpace string) *AssetFileSystem {
-	var (
-		ns *AssetFileSystem
-		ok bool
-	)
 	for _, name := range strings.Split(strings.Trim(nameSpace, "/"), "/") {
-		if fs.nameSpaces == nil {
-			fs.nameSpaces = make(map[string]*AssetFileSystem)
-		}
-		if ns, ok = fs.nameSpaces[name]; !ok {
-			path := name
-			if fs.path != "" {
-				path = filepath.Join(fs.path, path)
+		ns, ok := fs.load().nameSpaces.Get(name)
+		if !ok {
+			parent := fs
+			s := fs.update(func(s *fileSystemState) bool {
+				if ns, ok = s.nameSpaces.Get(name); ok {
+					return false
+				}
+				path := name
+				if parent.path != "" {
+					path = filepath.Join(parent.path, path)
+				}
+				ns = &AssetFileSystem{path: path, parent: parent, nameSpace: name}
+				ns.state.Store(&fileSystemState{plugins: s.plugins, nameSpaceCallbacks: s.nameSpaceCallbacks})
+				ns.init()
+				s.nameSpaces = s.nameSpaces.With(name, ns)
+				return true
+			})
+			if s != nil {
+				for _, cb := range s.nameSpaceCallbacks {
+					cb(ns)
+				}
 			}
-			ns = &AssetFileSystem{path: path, parent: fs, nameSpace: name, plugins: fs.plugins}
-			ns.init()
-			fs.nameSpaces[name] = ns
 		}
 		fs = ns
 	}
 	return fs
 }
 
-func (fs *AssetFileSystem) newPathNameSpace(pth string) assetfsapi.NameSpacedInterface {
-	ns := &AssetFileSystem{nameSpace: pth, path: pth}
-	ns.parent = fs
-	ns.init()
-	return ns
-}
-
-func (fs *AssetFileSystem) newRawFS(pth string) assetfsapi.Interface {
-	ns := &AssetFileSystem{paths: []string{pth}, path: fs.path}
+func (fs *AssetFileSystem) newRawFS(layer assetfsapi.Layer) assetfsapi.Interface {
+	ns := &AssetFileSystem{path: fs.path}
+	ns.state.Store(&fileSystemState{layers: []assetfsapi.Layer{layer}})
 	rfs := &RawFileSystem{ns}
 	rfs.init()
 	return rfs
@@ -269,120 +456,24 @@ func (fs *AssetFileSystem) GetParent() assetfsapi.Interface {
 	return fs.parent
 }
 
-func (fs *AssetFileSystem) eachPath(reverse bool, cb func(pth string) error) (err error) {
-	if reverse {
-		for _, pth := range fs.paths {
-			err = cb(pth)
-			if err != nil {
-				return err
-			}
-		}
-	} else {
-		for i := len(fs.paths); i > 0; i-- {
-			err = cb(fs.paths[i-1])
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return
-}
-
 func (fs *AssetFileSystem) ReadDir(dir string, cb assetfsapi.CbWalkInfoFunc, skipDir bool) (err error) {
-	return fs.readDir(dir, cb, false, skipDir)
+	return fs.readDir(context.Background(), dir, cb, false, skipDir)
 }
 
-func (fs *AssetFileSystem) readDir(dir string, cb assetfsapi.CbWalkInfoFunc, parentLookup bool, skipDir bool) (err error) {
-	if dir == "" {
-		dir = "."
-	}
-
-	if !skipDir && fs.nameSpaces != nil {
-		if dir == "." {
-			for nsName, ns := range fs.nameSpaces {
-				err = cb(&NameSpaceFileInfo{assetfsapi.NewCleanedBasicFileInfo(ns.path, nsName), ns})
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			parts := strings.SplitN(dir, string(os.PathSeparator), 2)
-			if ns, ok := fs.nameSpaces[parts[0]]; ok {
-				err = ns.readDir(parts[1], cb, false, skipDir)
-				if err != nil {
-					return err
-				}
-			}
-		}
-	}
-
-	dolsdir := func(root string) (err error) {
-		var inf assetfsapi.FileInfo
-		var realPath, pth string
-		ites, err := ioutil.ReadDir(root)
-		if err != nil {
-			return err
-		}
-		for _, info := range ites {
-			realPath = filepath.Join(root, info.Name())
-			pth = filepath.Join(fs.path, info.Name())
-			if info.IsDir() {
-				if skipDir {
-					return nil
-				}
-
-				inf = &RealDirFileInfo{&RealFileInfo{basicFileInfo(pth, info), realPath}}
-			} else {
-				inf = &RealFileInfo{basicFileInfo(pth, info), realPath}
-			}
-			err = cb(inf)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	}
-	if dir == "." {
-		for _, pth := range fs.paths {
-			err = dolsdir(pth)
-			if err != nil {
-				return err
-			}
-		}
-	} else {
-		for _, pth := range fs.paths {
-			pth = filepath.Join(pth, dir)
-			if path_helpers.IsExistingDir(pth) {
-				err = dolsdir(pth)
-				if err != nil {
-					return err
-				}
-			}
-		}
-	}
+// ReadDirLookUp reads the directory dir like ReadDir. If parentLookup, the directory dir of the namespace
+// into the parents is read too.
+func (fs *AssetFileSystem) ReadDirLookUp(dir string, cb assetfsapi.CbWalkInfoFunc, parentLookup, skipDir bool) (err error) {
+	return fs.readDir(context.Background(), dir, cb, parentLookup, skipDir)
+}
 
-	if err == nil && fs.parent != nil && parentLookup {
-		if dir == "" {
-			dir = fs.nameSpace
-		} else {
-			dir = fs.nameSpace + string(os.PathSeparator) + dir
-		}
-		return fs.parent.(*AssetFileSystem).readDir(dir, cb, parentLookup, skipDir)
-	}
-	return
+func (fs *AssetFileSystem) readDir(ctx context.Context, dir string, cb assetfsapi.CbWalkInfoFunc, parentLookup bool, skipDir bool) (err error) {
+	return fs.readDirLookUp(ctx, dir, cb, layerLookUp{nameSpaces: true, parents: parentLookup}, skipDir)
 }
 
+// PathsFrom calls cb with the real directory of each local source and directory layer which contains pth
 func (fs *AssetFileSystem) PathsFrom(ctx context.Context, pth string, cb func(pth string) error) (err error) {
-	var parent = fs
-	for parent != nil {
-		if parent.parent == nil {
-			break
-		}
-		parent = parent.parent.(*AssetFileSystem)
-	}
-
-	for _, src := range local.AllSources(fs.LocalSources(), ctx) {
-		if info, err := src.Get(pth); err != nil {
+	for _, src := range local.AllSources(fs.localSourcesRegister(), ctx) {
+		if info, err := src.Get(fs.rootPath(pth)); err != nil {
 			if !os.IsNotExist(err) {
 				return fmt.Errorf("source «%T» %s get info for %q failed: %v", src, src, pth, err)
 			}
@@ -393,82 +484,45 @@ func (fs *AssetFileSystem) PathsFrom(ctx context.Context, pth string, cb func(pt
 		}
 	}
 
-	err = fs.pathsFrom(ctx, pth, cb)
+	err = fs.eachLayer(pth, layerLookUp{nameSpaces: true, parents: true}, func(layer assetfsapi.Layer, pth string) error {
+		if l, ok := layer.(*DirLayer); ok {
+			if realPath := l.realPath(pth); path_helpers.IsExistingDir(realPath) {
+				return cb(realPath)
+			}
+		}
+		return nil
+	})
 	if err == io.EOF {
 		return nil
 	}
 	return
 }
 
-func (fs *AssetFileSystem) pathsFrom(ctx context.Context, dir string, cb func(pth string) error) (err error) {
-	if fs.pathsFromFunc != nil {
-		return fs.pathsFromFunc(ctx, dir, cb)
-	}
-
-	if dir == "" {
-		dir = "."
-	}
-
-	if dir == "." {
-		parent := fs
-		prefix := ""
-		for parent != nil {
-			for _, pth := range parent.paths {
-				pth = path.Join(pth, prefix)
-
-				if _, err = os.Stat(pth); err != nil {
-					if os.IsNotExist(err) {
-						err = nil
-						continue
-					}
-					return err
-				}
-
-				if err = cb(pth); err != nil {
-					return err
-				}
-			}
-			if parent.parent == nil {
-				return
-			}
-			prefix = filepath.Join(parent.nameSpace, prefix)
-			parent = parent.parent.(*AssetFileSystem)
-		}
-		return
-	}
-
-	if fs.nameSpaces != nil {
-		parts := strings.SplitN(dir, string(os.PathSeparator), 2)
-		if ns, ok := fs.nameSpaces[parts[0]]; ok {
-			return ns.pathsFrom(ctx, parts[1], cb)
-		}
-	}
-
-	x := fs.newPathNameSpace(dir).(*AssetFileSystem).pathsFrom(ctx, ".", cb)
-	return x
-}
-
 func (fs *AssetFileSystem) GetPaths(recursive ...bool) (p []*fileutils.Dir) {
 	rec := len(recursive) > 0 && recursive[0]
 	fspath := fs.path
 	if fspath == "" {
 		fspath = "."
 	}
-	for _, pth := range fs.paths {
-		p = append(p, &fileutils.Dir{Src: pth, Destation: fileutils.Destation{fspath}})
+	s := fs.load()
+	for _, layer := range s.layers {
+		if l, ok := layer.(*DirLayer); ok {
+			p = append(p, &fileutils.Dir{Src: l.Dir, Destation: fileutils.Destation{Dest: fspath}})
+		}
 	}
-	if rec && fs.nameSpaces != nil {
-		for _, ns := range fs.nameSpaces {
+	if rec {
+		s.nameSpaces.Each(func(_ string, ns *AssetFileSystem) error {
 			p = append(p, ns.GetPaths(true)...)
-		}
+			return nil
+		})
 	}
 	return
 }
 
 func (fs *AssetFileSystem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if fs.handler == nil {
+	fs.handlerOnce.Do(func() {
 		fs.handler = HttpStaticHandler(fs)
-	}
+	})
 	fs.handler.ServeHTTP(w, r)
 }
 
@@ -476,8 +530,12 @@ func (fs *AssetFileSystem) RegisterPlugin(plugins ...assetfsapi.Plugin) {
 	for _, p := range plugins {
 		p.Init(fs)
 	}
-	for _, pth := range fs.paths {
-		pthFS := fs.newPathNameSpace(pth)
+	s := fs.update(func(s *fileSystemState) bool {
+		s.plugins = append(s.plugins[:len(s.plugins):len(s.plugins)], plugins...)
+		return true
+	})
+	for _, layer := range s.layers {
+		pthFS := fs.newRawFS(layer)
 		for _, p := range plugins {
 			p.PathRegisterCallback(pthFS)
 		}
@@ -487,7 +545,6 @@ func (fs *AssetFileSystem) RegisterPlugin(plugins ...assetfsapi.Plugin) {
 			p.PathRegisterCallback(fs)
 		}
 	}
-	fs.plugins = append(fs.plugins, plugins...)
 }
 
 func (fs *AssetFileSystem) DumpFiles(cb func(info assetfsapi.FileInfo) error) error {
@@ -508,7 +565,7 @@ func (fs *AssetFileSystem) DumpC(ctx context.Context, cb func(info assetfsapi.Fi
 
 func (fs *AssetFileSystem) TreeNames(ctx context.Context, onlyFiles bool, ignore ...func(pth string) bool) (result []assetfsapi.FileInfo, err error) {
 	m := map[string]assetfsapi.FileInfo{}
-	err = fs.WalkInfo(".", func(info assetfsapi.FileInfo) error {
+	err = fs.WalkInfoC(ctx, ".", func(info assetfsapi.FileInfo) error {
 		pth := info.Path()
 		if pth == "." {
 			return nil
@@ -525,19 +582,6 @@ func (fs *AssetFileSystem) TreeNames(ctx context.Context, onlyFiles bool, ignore
 			}
 		}
 
-		if !info.IsDir() {
-			for _, src := range local.AllSources(fs.LocalSources(), ctx) {
-				if srcInfo, err := src.Get(pth); err != nil {
-					if !os.IsNotExist(err) {
-						return fmt.Errorf("source «%T» %s get info for %q failed: %v", src, src, pth, err)
-					}
-				} else if !srcInfo.IsDir() {
-					m[info.Path()] = &RealFileInfo{basicFileInfo(pth, srcInfo), srcInfo.Path()}
-					return nil
-				}
-			}
-		}
-
 		m[info.Path()] = info
 
 		return nil
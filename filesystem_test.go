@@ -0,0 +1,83 @@
+package assetfs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+// TestConcurrentRegister must be run with `go test -race`
+func TestConcurrentRegister(t *testing.T) {
+	const n = 20
+	fs := NewAssetFileSystem()
+	if err := fs.RegisterPath("t/data"); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 0; i < n; i++ {
+			if err := fs.NameSpaceFS(fmt.Sprintf("ns%d", i)).RegisterPath("t/data2"); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				info, err := fs.AssetInfoC(context.Background(), "a.txt")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if info.Path() != "a.txt" {
+					t.Errorf("bad path %q", info.Path())
+					return
+				}
+
+				var found bool
+				if err = fs.Walk(".", func(pth string, isDir bool) error {
+					if pth == "z/b/c.txt" {
+						found = true
+					}
+					return nil
+				}, assetfsapi.WalkAll); err != nil {
+					t.Error(err)
+					return
+				}
+				if !found {
+					t.Error("z/b/c.txt not walked")
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		pth := fmt.Sprintf("ns%d/z/a/x.txt", i)
+		if _, err := fs.AssetInfoC(context.Background(), pth); err != nil {
+			t.Errorf("%s: %v", pth, err)
+		}
+	}
+}
@@ -2,59 +2,32 @@ package assetfs
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
-	"strings"
+	"sort"
 
 	"github.com/moisespsena-go/assetfs/local"
 
 	"github.com/moisespsena-go/os-common"
 
 	"github.com/moisespsena-go/assetfs/assetfsapi"
-	"github.com/moisespsena-go/path-helpers"
 )
 
 var basicFileInfo = assetfsapi.OsFileInfoToBasic
 
 // Names list matched files from assetfs
-func filesystemGlob(fs *AssetFileSystem, pattern assetfsapi.GlobPattern, cb func(pth string, isDir bool) error) error {
-	return filesystemGlobInfo(fs, pattern, func(info assetfsapi.FileInfo) error {
+func filesystemGlob(ctx context.Context, fs *AssetFileSystem, pattern assetfsapi.GlobPattern, cb func(pth string, isDir bool) error) error {
+	return filesystemGlobInfo(ctx, fs, pattern, func(info assetfsapi.FileInfo) error {
 		return cb(info.Path(), info.IsDir())
 	})
 }
 
 // Names list matched files from assetfs
-func filesystemGlobInfo(fs *AssetFileSystem, pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
-	set := make(map[string]bool)
-	cb2 := func(info assetfsapi.FileInfo) error {
-		if info.IsDir() {
-			if !pattern.AllowDirs() {
-				return nil
-			}
-		} else {
-			if !pattern.AllowFiles() {
-				return nil
-			}
-		}
-		pth := info.Path()
-		ok := pattern.Match(filepath.Base(pth))
-		if !ok {
-			return nil
-		}
-		if _, ok := set[pth]; !ok {
-			if err := cb(info); err != nil {
-				return err
-			}
-			set[pth] = true
-		}
-		return nil
-	}
-	if pattern.IsRecursive() {
-		return fs.WalkInfo(pattern.Dir(), cb2, assetfsapi.WalkAll^assetfsapi.WalkDirs)
-	}
-	return fs.readDir(pattern.Dir(), cb2, true, true)
+func filesystemGlobInfo(ctx context.Context, fs *AssetFileSystem, pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
+	return globInfo(ctx, fs, pattern, cb)
 }
 
 // Asset get content with name from assetfs
@@ -72,147 +45,106 @@ func filesystemAsset(ctx context.Context, fs *AssetFileSystem, name string) (ass
 }
 
 func filesystemAssetInfo(ctx context.Context, fs *AssetFileSystem, pth string) (info assetfsapi.FileInfo, err error) {
-	var (
-		r    string
-		stat os.FileInfo
-	)
-	dir, base := path.Split(pth)
-	err = fs.PathsFrom(ctx, dir, func(pth string) (err error) {
-		pth = filepath.FromSlash(path.Join(pth, base))
-		if stat, err = os.Stat(pth); err == nil {
-			r = pth
-			return io.EOF
+	pth = cleanPath(pth)
+	if IsWhiteout(path.Base(pth)) {
+		return nil, oscommon.ErrNotFound(pth)
+	}
+	for _, src := range local.AllSources(fs.localSourcesRegister(), ctx) {
+		if srcInfo, err := src.Get(fs.rootPath(pth)); err != nil {
+			if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("source «%T» %s get info for %q failed: %v", src, src, pth, err)
+			}
+		} else {
+			return newRealFileInfo(pth, srcInfo.Path(), srcInfo), nil
 		}
-		return nil
+		if sourceWhitedOut(src, fs.rootPath(pth)) {
+			return nil, oscommon.ErrNotFound(pth)
+		}
+	}
+
+	if ns, nsPth := fs.nameSpaceOf(pth); nsPth == "." && ns != fs {
+		return &NameSpaceFileInfo{assetfsapi.NewCleanedBasicFileInfo(pth), ns}, nil
+	}
+
+	err = fs.eachLayer(pth, layerLookUp{nameSpaces: true, parents: true}, func(layer assetfsapi.Layer, lpth string) error {
+		linfo, err := layer.AssetInfoC(ctx, lpth)
+		if err != nil {
+			if isLayerSkipError(err) {
+				if layerWhitedOut(ctx, layer, lpth) {
+					return io.EOF
+				}
+				return nil
+			}
+			return err
+		}
+		info = fileInfoWithPath(linfo, pth)
+		return io.EOF
 	})
 	if err != nil && err != io.EOF {
 		return nil, err
 	}
-	if r == "" {
+	if info == nil {
 		return nil, oscommon.ErrNotFound(pth)
 	}
-	return &RealFileInfo{basicFileInfo(pth, stat), r}, nil
+	return info, nil
 }
 
-func filesystemWalk(fs *AssetFileSystem, dir string, cb assetfsapi.CbWalkInfoFunc, mode assetfsapi.WalkMode) (err error) {
-	if dir == "" {
-		dir = "."
+func filesystemWalk(ctx context.Context, fs *AssetFileSystem, dir string, cb assetfsapi.CbWalkInfoFunc, mode assetfsapi.WalkMode) (err error) {
+	lookUp := layerLookUp{nameSpaces: true, parents: mode.IsParentLookUp(), reverse: mode.IsReverse()}
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
-	if dir == "." {
-		if fs.nameSpaces != nil {
-			for _, ns := range fs.nameSpaces {
-				err = filesystemWalk(ns, ".", func(info assetfsapi.FileInfo) error {
-					npth := strings.TrimPrefix(ns.path, fs.path)
-					if npth[0] == '/' {
-						npth = npth[1:]
-					}
-					switch t := info.(type) {
-					case *RealDirFileInfo:
-						assetfsapi.SetBasicFileInfoPath(t.BasicFileInfo, filepath.Join(npth, t.Path()))
-					case *RealFileInfo:
-						assetfsapi.SetBasicFileInfoPath(t.BasicFileInfo, filepath.Join(npth, t.Path()))
-					}
-					return cb(info)
-				}, mode|assetfsapi.WalkNameSpacesLookUp^assetfsapi.WalkParentLookUp)
-				if err != nil {
-					return err
-				}
-			}
+	var walk func(dir string) error
+	walk = func(dir string) (err error) {
+		if err = ctx.Err(); err != nil {
+			return
 		}
-
-		if err != nil {
+		var infos []assetfsapi.FileInfo
+		if err = fs.readDirLookUp(ctx, dir, func(info assetfsapi.FileInfo) error {
+			infos = append(infos, info)
+			return nil
+		}, lookUp, false); err != nil {
 			return
 		}
 
-		err = fs.eachPath(mode.IsReverse(), func(root string) error {
-			return filepath.Walk(root, func(realPath string, info os.FileInfo, err error) error {
-				if err != nil {
-					return err
-				}
-				if realPath == "." || realPath == root {
-					return nil
-				}
-				pth := strings.TrimPrefix(realPath, root)
-				if pth[0] == filepath.Separator {
-					pth = pth[1:]
-				}
-
-				if info.IsDir() {
-					if !mode.IsDirs() {
-						return nil
-					}
-					return cb(&RealDirFileInfo{&RealFileInfo{basicFileInfo(pth, info), realPath}})
-				}
-
-				if !mode.IsFiles() {
-					return nil
+		if mode.IsSorted() {
+			sort.Slice(infos, func(i, j int) bool {
+				if mode.IsReverse() {
+					return infos[i].Name() > infos[j].Name()
 				}
-				return cb(&RealFileInfo{basicFileInfo(pth, info), realPath})
+				return infos[i].Name() < infos[j].Name()
 			})
-		})
-		if err != nil {
-			return
-		}
-	} else {
-		if mode.IsNameSpacesLookUp() && fs.nameSpaces != nil {
-			parts := strings.SplitN(dir, string(os.PathSeparator), 2)
-			if ns, ok := fs.nameSpaces[parts[0]]; ok {
-				err = filesystemWalk(ns, parts[1], cb, mode|assetfsapi.WalkNameSpacesLookUp^assetfsapi.WalkParentLookUp)
-				if err != nil {
-					return err
-				}
-			}
 		}
 
-		err = fs.eachPath(mode.IsReverse(), func(root string) (err error) {
-			root = filepath.Join(root, dir)
-			if path_helpers.IsExistingDir(root) {
-				err = filepath.Walk(root, func(realPath string, info os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
-					if realPath == "." || realPath == root {
-						return nil
-					}
-
-					pth := filepath.Join(fs.path, strings.TrimPrefix(realPath, root))
-					if pth[0] == filepath.Separator {
-						pth = pth[1:]
+		for _, info := range infos {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			if !info.IsDir() {
+				if mode.IsFiles() {
+					if err = cb(info); err != nil {
+						return
 					}
-
-					var inf assetfsapi.FileInfo
-
-					if info.IsDir() {
-						if !mode.IsDirs() {
-							return nil
-						}
-					} else {
-						if !mode.IsFiles() {
-							return nil
-						}
+				}
+				continue
+			}
+			if info.Type().IsNameSpace() && !mode.IsNameSpaces() {
+				continue
+			}
+			if mode.IsDirs() {
+				if err = cb(info); err != nil {
+					if err == filepath.SkipDir {
+						continue
 					}
-					inf = &RealDirFileInfo{&RealFileInfo{basicFileInfo(pth, info), realPath}}
-					return cb(inf)
-				})
+					return
+				}
+			}
+			if err = walk(info.Path()); err != nil {
+				return
 			}
-			return
-		})
-		if err != nil {
-			return
-		}
-	}
-
-	if err == nil && fs.parent != nil && mode.IsParentLookUp() {
-		if dir == "." {
-			dir = fs.nameSpace
-		} else {
-			dir = filepath.Join(fs.nameSpace, dir)
-		}
-		if mode.IsNameSpacesLookUp() {
-			mode ^= assetfsapi.WalkNameSpacesLookUp
 		}
-		return filesystemWalk(fs.parent.(*AssetFileSystem), dir, cb, mode)
+		return nil
 	}
-	return
+	return walk(cleanPath(dir))
 }
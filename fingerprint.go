@@ -0,0 +1,419 @@
+package assetfs
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+	"github.com/moisespsena-go/assetfs/local"
+)
+
+var (
+	// FingerprintSize number of hex digits of digest inserted into fingerprinted paths
+	FingerprintSize = 6
+	// FingerprintCacheControl Cache-Control header value of files served by fingerprinted path
+	FingerprintCacheControl = "public, max-age=31536000, immutable"
+)
+
+// AssetDigest returns the SHA-256 digest of the uncompressed contents of asset: the stored digest if any,
+// the digest of the local file (see local.Digest) for real files, otherwise computed from the contents.
+func AssetDigest(asset assetfsapi.FileInfo) (digest [sha256.Size]byte, err error) {
+	var ok bool
+	if digest, ok = digestOf(asset); ok {
+		return
+	}
+	for info := asset; ; {
+		if rf, isReal := info.(*RealFileInfo); isReal {
+			var d *[sha256.Size]byte
+			if d, err = local.Digest(rf.RealPath()); err != nil {
+				return
+			}
+			return *d, nil
+		}
+		u, isWrapper := info.(interface{ Unwrap() assetfsapi.FileInfo })
+		if !isWrapper {
+			break
+		}
+		info = u.Unwrap()
+	}
+
+	var r io.ReadCloser
+	if r, err = asset.Reader(); err != nil {
+		return
+	}
+	defer r.Close()
+
+	var src io.Reader = r
+	if c, ok := r.(Compresseder); ok && c.Compressed() {
+		if src, err = gzip.NewReader(r); err != nil {
+			return
+		}
+	}
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, src); err != nil {
+		return
+	}
+	copy(digest[:], hash.Sum(nil))
+	return
+}
+
+// Fingerprint returns pth with the first FingerprintSize hex digits of digest inserted before the
+// extension. Example: `css/app.css` to `css/app.3f9a1c.css`.
+func Fingerprint(pth string, digest [sha256.Size]byte) string {
+	ext := path.Ext(pth)
+	return strings.TrimSuffix(pth, ext) + "." + hex.EncodeToString(digest[:])[:FingerprintSize] + ext
+}
+
+// ParseFingerprint returns the original path and the fingerprint of fingerprinted pth. If pth isn't
+// fingerprinted, ok is false.
+func ParseFingerprint(pth string) (original, fingerprint string, ok bool) {
+	ext := path.Ext(pth)
+	base := strings.TrimSuffix(pth, ext)
+	if fingerprint = path.Ext(base); isFingerprint(fingerprint) {
+		return strings.TrimSuffix(base, fingerprint) + ext, fingerprint[1:], true
+	}
+	if isFingerprint(ext) {
+		// path without extension
+		return base, ext[1:], true
+	}
+	return "", "", false
+}
+
+// isFingerprint returns if ext is a dot followed by FingerprintSize lower hex digits
+func isFingerprint(ext string) bool {
+	if len(ext) != FingerprintSize+1 {
+		return false
+	}
+	for _, c := range ext[1:] {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+type fingerprintEntry struct {
+	realPath string
+	modTime  time.Time
+	size     int64
+	path     string
+	digest   [sha256.Size]byte
+	// data the contents with rewritten references, if the file type has a RefFinder
+	data []byte
+	// deps the fingerprinted paths of referenced files, by path
+	deps map[string]string
+}
+
+// refsEntry the references found in a file
+type refsEntry struct {
+	realPath string
+	modTime  time.Time
+	size     int64
+	data     []byte
+	refs     [][2]int
+	// targets the resolved path of each reference, or empty if it is not resolvable
+	targets []string
+}
+
+// Fingerprinter maps the paths of FS to fingerprinted paths. The digests are cached until the file
+// changes.
+type Fingerprinter struct {
+	FS assetfsapi.Interface
+	// Rewrite if the references of files with RefFinder (like CSS and HTML files) are rewritten to
+	// fingerprinted paths. The fingerprint of these files is the digest of rewritten contents, so it
+	// changes when a referenced file changes. References to HTML documents and cyclic references are
+	// not rewritten.
+	Rewrite bool
+
+	mu    sync.RWMutex
+	cache map[string]*fingerprintEntry
+	refs  map[string]*refsEntry
+}
+
+// NewFingerprinter create new Fingerprinter of fs
+func NewFingerprinter(fs assetfsapi.Interface) *Fingerprinter {
+	return &Fingerprinter{FS: fs}
+}
+
+// Path returns the fingerprinted path of pth
+func (f *Fingerprinter) Path(pth string) (string, error) {
+	return f.PathC(context.Background(), pth)
+}
+
+// PathC returns the fingerprinted path of pth, using context
+func (f *Fingerprinter) PathC(ctx context.Context, pth string) (string, error) {
+	e, err := f.entryOf(ctx, pth)
+	if err != nil {
+		return "", err
+	}
+	return e.path, nil
+}
+
+// Dependencies returns the paths of files referenced by pth, sorted
+func (f *Fingerprinter) Dependencies(ctx context.Context, pth string) (deps []string, err error) {
+	e, err := f.entryOf(ctx, pth)
+	if err != nil {
+		return nil, err
+	}
+	for dep := range e.deps {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+	return
+}
+
+// Graph returns the paths of files referenced by each file which references other files
+func (f *Fingerprinter) Graph(ctx context.Context) (graph map[string][]string, err error) {
+	graph = map[string][]string{}
+	err = f.walk(ctx, func(pth string, e *fingerprintEntry) error {
+		for dep := range e.deps {
+			graph[pth] = append(graph[pth], dep)
+		}
+		sort.Strings(graph[pth])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for pth, deps := range graph {
+		if len(deps) == 0 {
+			delete(graph, pth)
+		}
+	}
+	return
+}
+
+// Dependents returns the paths of files which references pth, directly or indirectly, sorted. Their
+// fingerprints changes when pth changes.
+func (f *Fingerprinter) Dependents(ctx context.Context, pth string) (dependents []string, err error) {
+	var graph map[string][]string
+	if graph, err = f.Graph(ctx); err != nil {
+		return
+	}
+	var (
+		found = map[string]bool{}
+		visit func(pth string)
+	)
+	visit = func(target string) {
+		for src, deps := range graph {
+			for _, dep := range deps {
+				if dep == target && !found[src] {
+					found[src] = true
+					dependents = append(dependents, src)
+					visit(src)
+				}
+			}
+		}
+	}
+	visit(cleanPath(pth))
+	sort.Strings(dependents)
+	return
+}
+
+// fileInfo returns the file pth with rewritten references, or nil if it has not references to rewrite
+func (f *Fingerprinter) fileInfo(ctx context.Context, pth string, info assetfsapi.FileInfo) (assetfsapi.FileInfo, error) {
+	e, err := f.entry(ctx, cleanPath(pth), info)
+	if err != nil || e.data == nil {
+		return nil, err
+	}
+	return &rewrittenFileInfo{info, e.data, e.digest}, nil
+}
+
+func (f *Fingerprinter) entryOf(ctx context.Context, pth string) (*fingerprintEntry, error) {
+	pth = cleanPath(pth)
+	info, err := f.FS.AssetInfoC(ctx, pth)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, IS_DIR_ERROR
+	}
+	return f.entry(ctx, pth, info)
+}
+
+// entry returns the cached entry of pth, or computes it if the file or a referenced file changes
+func (f *Fingerprinter) entry(ctx context.Context, pth string, info assetfsapi.FileInfo) (e *fingerprintEntry, err error) {
+	f.mu.RLock()
+	e = f.cache[pth]
+	f.mu.RUnlock()
+	if e != nil && e.realPath == info.RealPath() && e.modTime.Equal(info.ModTime()) && e.size == info.Size() &&
+		f.depsValid(ctx, e) {
+		return
+	}
+
+	e = &fingerprintEntry{realPath: info.RealPath(), modTime: info.ModTime(), size: info.Size()}
+	if f.refFinder(pth) != nil {
+		var refs *refsEntry
+		if refs, err = f.refsOf(pth, info); err != nil {
+			return nil, err
+		}
+		if e.data, e.deps, err = f.rewrite(ctx, pth, refs); err != nil {
+			return nil, err
+		}
+		e.digest = sha256.Sum256(e.data)
+	} else if e.digest, err = AssetDigest(info); err != nil {
+		return nil, err
+	}
+	e.path = Fingerprint(pth, e.digest)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cache == nil {
+		f.cache = map[string]*fingerprintEntry{}
+	}
+	f.cache[pth] = e
+	return
+}
+
+// depsValid returns if the fingerprinted paths of files referenced by e does not changes
+func (f *Fingerprinter) depsValid(ctx context.Context, e *fingerprintEntry) bool {
+	for dep, fingerprinted := range e.deps {
+		info, err := f.FS.AssetInfoC(ctx, dep)
+		if err != nil || info.IsDir() {
+			return false
+		}
+		de, err := f.entry(ctx, dep, info)
+		if err != nil || de.path != fingerprinted {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Fingerprinter) refFinder(pth string) RefFinder {
+	if !f.Rewrite {
+		return nil
+	}
+	return RefFinders[strings.ToLower(path.Ext(pth))]
+}
+
+// refsOf returns the cached references found in pth, or finds it if the file changes
+func (f *Fingerprinter) refsOf(pth string, info assetfsapi.FileInfo) (e *refsEntry, err error) {
+	f.mu.RLock()
+	e = f.refs[pth]
+	f.mu.RUnlock()
+	if e != nil && e.realPath == info.RealPath() && e.modTime.Equal(info.ModTime()) && e.size == info.Size() {
+		return
+	}
+
+	e = &refsEntry{realPath: info.RealPath(), modTime: info.ModTime(), size: info.Size()}
+	if e.data, err = Data(info); err != nil {
+		return nil, err
+	}
+	e.refs = f.refFinder(pth)(e.data)
+	e.targets = make([]string, len(e.refs))
+	for i, ref := range e.refs {
+		if target, _, _, ok := resolveRef(pth, string(e.data[ref[0]:ref[1]])); ok && !isDocument(target) {
+			e.targets[i] = target
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.refs == nil {
+		f.refs = map[string]*refsEntry{}
+	}
+	f.refs[pth] = e
+	return
+}
+
+// reaches returns if the file from references the file to, directly or indirectly
+func (f *Fingerprinter) reaches(ctx context.Context, from, to string, seen map[string]bool) bool {
+	if from == to {
+		return true
+	}
+	if seen[from] || f.refFinder(from) == nil {
+		return false
+	}
+	seen[from] = true
+	info, err := f.FS.AssetInfoC(ctx, from)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	refs, err := f.refsOf(from, info)
+	if err != nil {
+		return false
+	}
+	for _, target := range refs.targets {
+		if target != "" && f.reaches(ctx, target, to, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// rewrite returns the data of pth with references replaced by fingerprinted paths, and the fingerprinted
+// paths of referenced files
+func (f *Fingerprinter) rewrite(ctx context.Context, pth string, refs *refsEntry) (out []byte, deps map[string]string, err error) {
+	deps = map[string]string{}
+	out = make([]byte, 0, len(refs.data))
+	var last int
+	for i, ref := range refs.refs {
+		target := refs.targets[i]
+		if target == "" || f.reaches(ctx, target, pth, map[string]bool{}) {
+			continue
+		}
+		info, err := f.FS.AssetInfoC(ctx, target)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		var te *fingerprintEntry
+		if te, err = f.entry(ctx, target, info); err != nil {
+			return nil, nil, err
+		}
+		_, refPath, suffix, _ := resolveRef(pth, string(refs.data[ref[0]:ref[1]]))
+		deps[target] = te.path
+		out = append(out, refs.data[last:ref[0]]...)
+		out = append(out, Fingerprint(refPath, te.digest)+suffix...)
+		last = ref[1]
+	}
+	out = append(out, refs.data[last:]...)
+	return
+}
+
+// walk calls cb with the entry of each file, including files of namespaces
+func (f *Fingerprinter) walk(ctx context.Context, cb func(pth string, e *fingerprintEntry) error) error {
+	return f.FS.WalkInfo(".", func(info assetfsapi.FileInfo) error {
+		if info.IsDir() {
+			return nil
+		}
+		e, err := f.entry(ctx, info.Path(), info)
+		if err != nil {
+			return err
+		}
+		return cb(info.Path(), e)
+	}, assetfsapi.WalkFiles|assetfsapi.WalkNameSpaces|assetfsapi.WalkParentLookUp)
+}
+
+// Manifest returns the fingerprinted paths of all files, including files of namespaces, by path
+func (f *Fingerprinter) Manifest() (manifest map[string]string, err error) {
+	manifest = map[string]string{}
+	if err = f.walk(context.Background(), func(pth string, e *fingerprintEntry) error {
+		manifest[pth] = e.path
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// WriteManifest writes the JSON object of Manifest to w, with sorted keys
+func (f *Fingerprinter) WriteManifest(w io.Writer) error {
+	manifest, err := f.Manifest()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(manifest)
+}
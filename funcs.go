@@ -0,0 +1,193 @@
+package assetfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"html/template"
+	"strings"
+	"sync"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+// DefaultIntegrityAlgorithm default hash algorithm of Subresource Integrity strings
+var DefaultIntegrityAlgorithm = "sha384"
+
+type integrityEntry struct {
+	version   string
+	integrity string
+}
+
+// TemplateFuncs helper functions of templates for the assets of FS
+type TemplateFuncs struct {
+	FS assetfsapi.Interface
+	// Prefix the URL prefix of assets, like `/static/`
+	Prefix string
+	// Fingerprinter if set, URLs are fingerprinted and the integrity and contents of files with rewritten
+	// references are of the rewritten contents
+	Fingerprinter *Fingerprinter
+
+	mu        sync.RWMutex
+	integrity map[string]integrityEntry
+}
+
+// NewTemplateFuncs create new TemplateFuncs of fs
+func NewTemplateFuncs(fs assetfsapi.Interface, prefix string) *TemplateFuncs {
+	return &TemplateFuncs{FS: fs, Prefix: prefix}
+}
+
+// FuncMap returns the template functions `asset_url`, `asset_integrity`, `asset_data`, `asset_exists` and
+// the trusted contents functions `asset_html_trusted`, `asset_css_trusted` and `asset_js_trusted`
+func (t *TemplateFuncs) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"asset_url":          t.URL,
+		"asset_integrity":    t.Integrity,
+		"asset_data":         t.Data,
+		"asset_html_trusted": t.HTMLTrusted,
+		"asset_css_trusted":  t.CSSTrusted,
+		"asset_js_trusted":   t.JSTrusted,
+		"asset_exists":       t.Exists,
+	}
+}
+
+// fileInfo returns the file pth, with rewritten contents if has references rewritten by Fingerprinter
+func (t *TemplateFuncs) fileInfo(pth string) (info assetfsapi.FileInfo, err error) {
+	ctx := context.Background()
+	if info, err = t.FS.AssetInfoC(ctx, pth); err != nil {
+		return
+	}
+	if info.IsDir() {
+		return nil, IS_DIR_ERROR
+	}
+	if t.Fingerprinter != nil {
+		var rewritten assetfsapi.FileInfo
+		if rewritten, err = t.Fingerprinter.fileInfo(ctx, pth, info); err != nil {
+			return nil, err
+		}
+		if rewritten != nil {
+			info = rewritten
+		}
+	}
+	return
+}
+
+// URL returns the URL of pth, fingerprinted if Fingerprinter is set
+func (t *TemplateFuncs) URL(pth string) (string, error) {
+	pth = cleanPath(pth)
+	if t.Fingerprinter != nil {
+		var err error
+		if pth, err = t.Fingerprinter.Path(pth); err != nil {
+			return "", err
+		}
+	} else if _, err := t.FS.AssetInfo(pth); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(t.Prefix, "/") + "/" + pth, nil
+}
+
+// Integrity returns the Subresource Integrity string of pth, like `sha384-<base64 digest>`. The algorithm
+// is `sha256`, `sha384` or `sha512`. If algorithm is omitted, uses DefaultIntegrityAlgorithm. The integrity
+// is computed once and reused until the file changes.
+func (t *TemplateFuncs) Integrity(pth string, algorithm ...string) (integrity string, err error) {
+	alg := DefaultIntegrityAlgorithm
+	if len(algorithm) > 0 && algorithm[0] != "" {
+		alg = algorithm[0]
+	}
+	var newHash func() hash.Hash
+	switch alg {
+	case "sha256":
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return "", fmt.Errorf("unsupported integrity algorithm %q", alg)
+	}
+
+	pth = cleanPath(pth)
+	var info assetfsapi.FileInfo
+	if info, err = t.fileInfo(pth); err != nil {
+		return
+	}
+	var (
+		key     = alg + ":" + pth
+		version = fmt.Sprint(info.RealPath(), info.ModTime(), info.Size())
+	)
+	if r, ok := info.(*rewrittenFileInfo); ok {
+		version += fmt.Sprintf(":%x", r.digest)
+	}
+
+	t.mu.RLock()
+	e, ok := t.integrity[key]
+	t.mu.RUnlock()
+	if ok && e.version == version {
+		return e.integrity, nil
+	}
+
+	var sum []byte
+	if newHash == nil {
+		var digest [sha256.Size]byte
+		if digest, err = AssetDigest(info); err != nil {
+			return
+		}
+		sum = digest[:]
+	} else {
+		var data []byte
+		if data, err = Data(info); err != nil {
+			return
+		}
+		h := newHash()
+		h.Write(data)
+		sum = h.Sum(nil)
+	}
+	integrity = alg + "-" + base64.StdEncoding.EncodeToString(sum)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.integrity == nil {
+		t.integrity = map[string]integrityEntry{}
+	}
+	t.integrity[key] = integrityEntry{version, integrity}
+	return
+}
+
+// Data returns the uncompressed contents of pth, to inline it. The contents are escaped by templates: use
+// HTMLTrusted, CSSTrusted or JSTrusted to inline trusted contents.
+func (t *TemplateFuncs) Data(pth string) (string, error) {
+	info, err := t.fileInfo(cleanPath(pth))
+	if err != nil {
+		return "", err
+	}
+	return DataS(info)
+}
+
+// HTMLTrusted returns the uncompressed contents of pth as trusted HTML, which is not escaped by templates.
+// Use it only with files of trusted sources.
+func (t *TemplateFuncs) HTMLTrusted(pth string) (template.HTML, error) {
+	s, err := t.Data(pth)
+	return template.HTML(s), err
+}
+
+// CSSTrusted returns the uncompressed contents of pth as trusted CSS, which is not escaped by templates.
+// Use it only with files of trusted sources.
+func (t *TemplateFuncs) CSSTrusted(pth string) (template.CSS, error) {
+	s, err := t.Data(pth)
+	return template.CSS(s), err
+}
+
+// JSTrusted returns the uncompressed contents of pth as trusted JavaScript, which is not escaped by
+// templates. Use it only with files of trusted sources.
+func (t *TemplateFuncs) JSTrusted(pth string) (template.JS, error) {
+	s, err := t.Data(pth)
+	return template.JS(s), err
+}
+
+// Exists returns if the file pth exists
+func (t *TemplateFuncs) Exists(pth string) bool {
+	info, err := t.FS.AssetInfo(cleanPath(pth))
+	return err == nil && !info.IsDir()
+}
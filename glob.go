@@ -1,6 +1,7 @@
 package assetfs
 
 import (
+	"context"
 	"path"
 	"sort"
 	"strings"
@@ -8,6 +9,7 @@ import (
 	"github.com/gobwas/glob"
 	"github.com/gobwas/glob/syntax"
 	"github.com/moisespsena-go/assetfs/assetfsapi"
+	oscommon "github.com/moisespsena-go/os-common"
 )
 
 type GlobPatter = assetfsapi.GlobPattern
@@ -151,6 +153,44 @@ func NewGlobPattern(pattern string) assetfsapi.GlobPattern {
 
 var G = NewGlobPattern
 
+// globInfo calls cb for each entry of fs matched by pattern, using the fs traversal methods. If fs has
+// traversal methods with context, they are used with ctx.
+func globInfo(ctx context.Context, fs assetfsapi.Interface, pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
+	cb2 := func(info assetfsapi.FileInfo) error {
+		if info.IsDir() {
+			if !pattern.AllowDirs() {
+				return nil
+			}
+		} else if !pattern.AllowFiles() {
+			return nil
+		}
+		if !pattern.Match(path.Base(info.Path())) {
+			return nil
+		}
+		return cb(info)
+	}
+	if pattern.IsRecursive() {
+		mode := assetfsapi.WalkAll
+		if !pattern.AllowDirs() {
+			mode ^= assetfsapi.WalkDirs
+		}
+		if ct, ok := fs.(assetfsapi.ContextTraversableInterface); ok {
+			return ct.WalkInfoC(ctx, pattern.Dir(), cb2, mode)
+		}
+		return fs.WalkInfo(pattern.Dir(), cancelableCb(ctx, cb2), mode)
+	}
+	var err error
+	if ct, ok := fs.(assetfsapi.ContextTraversableInterface); ok {
+		err = ct.ReadDirC(ctx, pattern.Dir(), cb2, !pattern.AllowDirs())
+	} else {
+		err = fs.ReadDir(pattern.Dir(), cancelableCb(ctx, cb2), !pattern.AllowDirs())
+	}
+	if err != nil && !oscommon.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
 type Glob struct {
 	fs      assetfsapi.Interface
 	pattern assetfsapi.GlobPattern
@@ -244,3 +284,41 @@ func (g *Glob) InfosOrPanic() []assetfsapi.FileInfo {
 	}
 	return items
 }
+
+func (g *Glob) NameC(ctx context.Context, cb func(pth string, isDir bool) error) error {
+	if ct, ok := g.fs.(assetfsapi.ContextTraversableInterface); ok {
+		return ct.GlobC(ctx, g.pattern, cb)
+	}
+	return g.fs.Glob(g.pattern, func(pth string, isDir bool) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return cb(pth, isDir)
+	})
+}
+
+func (g *Glob) NamesC(ctx context.Context) (items []string, err error) {
+	err = g.NameC(ctx, func(pth string, isDir bool) error {
+		items = append(items, pth)
+		return nil
+	})
+	return
+}
+
+func (g *Glob) InfoC(ctx context.Context, cb func(info assetfsapi.FileInfo) error) error {
+	if ct, ok := g.fs.(assetfsapi.ContextTraversableInterface); ok {
+		return ct.GlobInfoC(ctx, g.pattern, cb)
+	}
+	return g.fs.GlobInfo(g.pattern, cancelableCb(ctx, cb))
+}
+
+func (g *Glob) InfosC(ctx context.Context) (items []assetfsapi.FileInfo, err error) {
+	err = g.InfoC(ctx, func(info assetfsapi.FileInfo) error {
+		items = append(items, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return
+}
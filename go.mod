@@ -1,28 +1,41 @@
 module github.com/moisespsena-go/assetfs
 
-go 1.14
+go 1.22
 
 require (
-	github.com/felixge/tcpkeepalive v0.0.0-20160804073959-5bb0b2dea91e // indirect
-	github.com/go-chi/chi v4.1.1+incompatible // indirect
+	github.com/andybalholm/brotli v1.0.6
+	github.com/go-chi/chi v4.1.1+incompatible
 	github.com/go-errors/errors v1.0.2
 	github.com/gobwas/glob v0.2.3
-	github.com/klauspost/compress v1.10.5
-	github.com/maruel/panicparse v1.4.1 // indirect
-	github.com/moisespsena-go/default-logger v0.0.0-20191023151346-68eb5ce996c1 // indirect
-	github.com/moisespsena-go/error-wrap v0.0.0-20190401221633-16a254c7a0f6 // indirect
+	github.com/klauspost/compress v1.18.0
 	github.com/moisespsena-go/file-utils v0.0.0-20190401220920-85c17946ea65
 	github.com/moisespsena-go/http-common v0.0.0-20190131203920-d04a3f750ad8
 	github.com/moisespsena-go/httpu v0.0.0-20200313203958-b3255810c425
 	github.com/moisespsena-go/io-common v0.0.1
-	github.com/moisespsena-go/logging v0.0.1 // indirect
-	github.com/moisespsena-go/middleware v0.0.0-20200313204045-6c5e6142ed90 // indirect
 	github.com/moisespsena-go/os-common v0.0.0-20190613183041-3ed619843d2b
 	github.com/moisespsena-go/path-helpers v0.0.1
-	github.com/moisespsena-go/task v0.0.0-20200206142025-cc2ce8a81ecc // indirect
-	github.com/moisespsena-go/tracederror v0.0.0-20200313204331-c667eb22a347 // indirect
 	github.com/moisespsena/orderedmap v0.0.0-20170706045105-61d33b4465c3
 	github.com/pkg/errors v0.9.1
-	golang.org/x/net v0.0.0-20200519113804-d87ec0cfa476 // indirect
 	gopkg.in/djherbis/times.v1 v1.2.0
 )
+
+require (
+	github.com/felixge/tcpkeepalive v0.0.0-20160804073959-5bb0b2dea91e // indirect
+	github.com/google/go-cmp v0.4.0 // indirect
+	github.com/maruel/panicparse v1.4.1 // indirect
+	github.com/mattn/go-colorable v0.1.6 // indirect
+	github.com/mattn/go-isatty v0.0.12 // indirect
+	github.com/mgutz/ansi v0.0.0-20170206155736-9520e82c474b // indirect
+	github.com/moisespsena-go/default-logger v0.0.0-20191023151346-68eb5ce996c1 // indirect
+	github.com/moisespsena-go/error-wrap v0.0.0-20190401221633-16a254c7a0f6 // indirect
+	github.com/moisespsena-go/logging v0.0.1 // indirect
+	github.com/moisespsena-go/middleware v0.0.0-20200313204045-6c5e6142ed90 // indirect
+	github.com/moisespsena-go/task v0.0.0-20200206142025-cc2ce8a81ecc // indirect
+	github.com/moisespsena-go/tracederror v0.0.0-20200313204331-c667eb22a347 // indirect
+	github.com/phayes/permbits v0.0.0-20190612203442-39d7c581d2ee // indirect
+	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2 // indirect
+	golang.org/x/net v0.0.0-20200519113804-d87ec0cfa476 // indirect
+	golang.org/x/sys v0.0.0-20200323222414-85ca7c5b95cd // indirect
+	golang.org/x/text v0.3.0 // indirect
+	golang.org/x/xerrors v0.0.0-20191204190536-9bdfabe68543 // indirect
+)
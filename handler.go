@@ -1,120 +1,564 @@
 package assetfs
 
 import (
-	"crypto/md5"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/gobwas/glob"
+
 	"github.com/moisespsena-go/assetfs/assetfsapi"
-	"github.com/moisespsena-go/httpu"
 )
 
-var cacheSince = time.Now()
+// CachePolicy Cache-Control header value of the paths matched by Pattern
+type CachePolicy struct {
+	// Pattern glob pattern of slash separated paths relative to the handler file system. `*` does not match
+	// the separator and `**` matches any sequence.
+	Pattern      string
+	CacheControl string
+	glob         glob.Glob
+}
+
+// NewCachePolicy create new CachePolicy
+func NewCachePolicy(pattern, cacheControl string) (policy *CachePolicy, err error) {
+	policy = &CachePolicy{Pattern: pattern, CacheControl: cacheControl}
+	if policy.glob, err = glob.Compile(pattern, '/'); err != nil {
+		return nil, fmt.Errorf("cache policy pattern %q: %v", pattern, err)
+	}
+	return
+}
+
+// Match returns if the policy applies to pth
+func (p *CachePolicy) Match(pth string) bool {
+	if p.glob == nil {
+		ok, _ := path.Match(p.Pattern, pth)
+		return ok
+	}
+	return p.glob.Match(pth)
+}
 
 type StaticHandler struct {
 	EtagTimeLife time.Duration
-	cacheSince   string
+	// CacheControl default Cache-Control header value. If empty, the header is not set.
+	CacheControl string
+	// CachePolicies Cache-Control header values by path. The first matched policy wins over CacheControl.
+	CachePolicies []*CachePolicy
 
-	FS   assetfsapi.Interface
-	etag map[string]struct {
-		last time.Time
-		sum  []byte
-	}
+	FS     assetfsapi.Interface
+	etag   map[string]*etagEntry
 	etagMu sync.RWMutex
-	gziped bool
+	// NoVariantsTimeLife duration of the cache of files without precompressed variants. If zero, the
+	// variants are looked up on each request.
+	NoVariantsTimeLife time.Duration
+	noVariants         map[string]*etagEntry
+	noVariantsMu       sync.RWMutex
+	// Encodings precompressed variants to look up and encodings of on the fly compression, in preference
+	// order. If nil, uses ContentEncodings.
+	Encodings []ContentEncoding
+	// CompressCache cache of files compressed on the fly. If nil, files are not compressed on the fly.
+	CompressCache *CompressCache
+	// CompressTypes media types, or prefixes of media types, to compress on the fly. If nil, uses
+	// CompressibleTypes.
+	CompressTypes []string
+	// CompressMinSize files smaller than it are not compressed on the fly
+	CompressMinSize int64
+	// DirIndex if directories without `index.html` file are served by its listing
+	DirIndex bool
+	// Fallback path of document served for not found paths without extension, like the `index.html` of a
+	// single page application. It is not used if Next is set.
+	Fallback string
+	// ErrorPages if error responses are served by the nearest `<status code>.html` file (like `404.html`)
+	// of the requested path directory or its parents, so each namespace can have its own pages.
+	ErrorPages bool
+	// Next handler of not found paths. Set it to use StaticHandler as middleware.
+	Next http.Handler
+	// NotFound if ServeHTTP responds with not found status if the file does not exists, and it is not
+	// handled by Next or Fallback. Otherwise, the response is empty.
+	NotFound bool
+	// Fingerprints if fingerprinted paths (see Fingerprint) are served by the file of original path with
+	// FingerprintCacheControl, when the fingerprint matches its digest
+	Fingerprints bool
+	// Fingerprinter if set, resolves fingerprinted paths instead of the digest of files, and the files with
+	// references rewritten by it are served with the rewritten contents
+	Fingerprinter *Fingerprinter
 }
 
 func (this *StaticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	this.ServeAsset(w, r, r.URL.Path)
+	this.serveAsset(w, r, r.URL.Path, this.Next, this.NotFound)
+}
+
+// Middleware returns a handler which serves the files and calls next for not found paths, instead of Next
+// handler. The handler is not changed, so it can be used by many middlewares.
+func (this *StaticHandler) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		this.serveAsset(w, r, r.URL.Path, next, this.NotFound)
+	})
 }
 
 func NewStaticHandler(fs assetfsapi.Interface) *StaticHandler {
 	return &StaticHandler{
-		FS:           fs,
-		EtagTimeLife: time.Hour,
-		cacheSince:   time.Now().Format(http.TimeFormat),
+		FS:                 fs,
+		EtagTimeLife:       time.Hour,
+		NoVariantsTimeLife: time.Minute,
+		CompressCache:      NewCompressCache(DefaultCompressCacheSize),
+		CompressMinSize:    DefaultCompressMinSize,
 	}
 }
 
-func (this *StaticHandler) getEtag(asset assetfsapi.FileInfo) (etag string, err error) {
-	var key = fmt.Sprint(asset.Path(), asset.ModTime(), asset.Size())
-	if this.etag != nil {
-		func() {
-			defer this.etagMu.RUnlock()
-			this.etagMu.RLock()
-			if value, ok2 := this.etag[key]; ok2 && value.last.Add(this.EtagTimeLife).After(time.Now()) {
-				etag = string(value.sum)
-			}
-		}()
-		if etag != "" {
+// AddCachePolicy add Cache-Control value for paths matched by glob pattern
+func (this *StaticHandler) AddCachePolicy(pattern, cacheControl string) error {
+	policy, err := NewCachePolicy(pattern, cacheControl)
+	if err != nil {
+		return err
+	}
+	this.CachePolicies = append(this.CachePolicies, policy)
+	return nil
+}
+
+// AddNameSpaceCachePolicy add Cache-Control value for all paths of namespace
+func (this *StaticHandler) AddNameSpaceCachePolicy(nameSpace, cacheControl string) error {
+	return this.AddCachePolicy(strings.Trim(nameSpace, "/")+"/**", cacheControl)
+}
+
+// GetCacheControl returns the Cache-Control header value of pth
+func (this *StaticHandler) GetCacheControl(pth string) string {
+	for _, p := range this.CachePolicies {
+		if p.Match(pth) {
+			return p.CacheControl
+		}
+	}
+	return this.CacheControl
+}
+
+// digestOf returns the stored digest of asset, if any
+func digestOf(asset assetfsapi.FileInfo) (digest [sha256.Size]byte, ok bool) {
+	for {
+		if d, isDigester := asset.(Digester); isDigester {
+			digest = d.Digest()
+			return digest, digest != [sha256.Size]byte{}
+		}
+		u, isWrapper := asset.(interface{ Unwrap() assetfsapi.FileInfo })
+		if !isWrapper {
 			return
 		}
+		asset = u.Unwrap()
+	}
+}
+
+// etagEntry cached entity tag of a path, or cached absence of variants of a path
+type etagEntry struct {
+	key  string
+	last time.Time
+	sum  []byte
+}
+
+// getEtag returns the strong entity tag of the uncompressed contents of asset, from the stored SHA-256 digest
+// or computed and cached by EtagTimeLife. The cache holds one entry by path, so the entry of a changed file
+// is replaced.
+func (this *StaticHandler) getEtag(asset assetfsapi.FileInfo) (etag string, err error) {
+	if digest, ok := digestOf(asset); ok {
+		return fmt.Sprintf(`"%x"`, digest), nil
 	}
 
-	hash := md5.New()
-	var r io.ReadCloser
-	if r, err = asset.Reader(); err != nil {
+	var (
+		pth = asset.Path()
+		key = fmt.Sprint(asset.RealPath(), asset.ModTime(), asset.Size())
+	)
+	func() {
+		defer this.etagMu.RUnlock()
+		this.etagMu.RLock()
+		if value, ok := this.etag[pth]; ok && value.key == key && value.last.Add(this.EtagTimeLife).After(time.Now()) {
+			etag = fmt.Sprintf(`"%x"`, value.sum)
+		}
+	}()
+	if etag != "" {
 		return
 	}
-	defer r.Close()
 
-	if _, err = io.Copy(hash, r); err != nil {
+	var digest [sha256.Size]byte
+	if digest, err = AssetDigest(asset); err != nil {
 		return
 	}
 
-	sum := hash.Sum(nil)
-	etag = fmt.Sprintf("%x", sum)
+	sum := digest[:]
+	etag = fmt.Sprintf(`"%x"`, sum)
 
 	this.etagMu.Lock()
 	defer this.etagMu.Unlock()
 
 	if this.etag == nil {
-		this.etag = map[string]struct {
-			last time.Time
-			sum  []byte
-		}{}
-	}
-	this.etag[key] = struct {
-		last time.Time
-		sum  []byte
-	}{time.Now(), sum}
+		this.etag = map[string]*etagEntry{}
+	}
+	this.etag[pth] = &etagEntry{key, time.Now(), sum}
 	return
 }
 
+// variant precompressed variant of a path
+type variant struct {
+	encoding string
+	info     assetfsapi.FileInfo
+}
+
+// variants returns the precompressed variants of pth, in preference order. If asset isn't nil, the absence
+// of variants is cached by NoVariantsTimeLife.
+func (this *StaticHandler) variants(ctx context.Context, pth string, asset assetfsapi.FileInfo) (variants []variant) {
+	var key string
+	if asset != nil && this.NoVariantsTimeLife > 0 {
+		key = fmt.Sprint(asset.RealPath(), asset.ModTime(), asset.Size())
+		this.noVariantsMu.RLock()
+		value, ok := this.noVariants[pth]
+		this.noVariantsMu.RUnlock()
+		if ok && value.key == key && value.last.Add(this.NoVariantsTimeLife).After(time.Now()) {
+			return nil
+		}
+	}
+
+	encodings := this.Encodings
+	if encodings == nil {
+		encodings = ContentEncodings
+	}
+	for _, enc := range encodings {
+		if info, err := this.FS.AssetInfoC(ctx, pth+enc.Ext); err == nil && !info.IsDir() {
+			variants = append(variants, variant{enc.Name, info})
+		}
+	}
+
+	if key != "" {
+		this.noVariantsMu.Lock()
+		defer this.noVariantsMu.Unlock()
+		if len(variants) > 0 {
+			delete(this.noVariants, pth)
+		} else {
+			if this.noVariants == nil {
+				this.noVariants = map[string]*etagEntry{}
+			}
+			this.noVariants[pth] = &etagEntry{key: key, last: time.Now()}
+		}
+	}
+	return
+}
+
+// ServeAsset serves the file pth. Directories are served by its `index.html` file or, if DirIndex, by its
+// listing. The precompressed variant preferred by client is served if exists,
+// otherwise the file or, if it does not exists, a variant decoded on the fly. If notFound, responds with
+// not found status if the file and variants does not exists.
 func (this *StaticHandler) ServeAsset(w http.ResponseWriter, r *http.Request, pth string, notFound ...bool) {
+	this.serveAsset(w, r, pth, this.Next, len(notFound) > 0 && notFound[0])
+}
+
+func (this *StaticHandler) serveAsset(w http.ResponseWriter, r *http.Request, pth string, next http.Handler, notFound bool) {
 	if fspath := RootPath(this.FS); fspath != "" {
 		pth = strings.TrimPrefix(pth, fspath)
 	}
 
 	pth = strings.TrimPrefix(pth, "/")
 
-	if asset, err := this.FS.AssetInfoC(r.Context(), pth); err == nil {
-		var rc io.ReadCloser
-		if rc, err = asset.Reader(); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+	asset, err := this.FS.AssetInfoC(r.Context(), pth)
+	if err != nil {
+		asset = nil
+		if this.Fingerprints || this.Fingerprinter != nil {
+			if original := this.unfingerprint(r, pth); original != nil {
+				pth, asset = original.Path(), original
+				w.Header().Set("Cache-Control", FingerprintCacheControl)
+			}
+		}
+	} else if asset.IsDir() {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			assetfsapi.DirRedirect(w, r)
 			return
 		}
-		defer rc.Close()
-		if cmpr, ok := rc.(Compresseder); ok && cmpr.Compressed() {
-			httpu.ServeContent(w, r, path.Base(pth), cacheSince, rc, func() (int64, error) {
-				return asset.Size(), nil
-			})
+		if index, err := this.FS.AssetInfoC(r.Context(), path.Join(pth, "index.html")); err == nil && !index.IsDir() {
+			pth, asset = index.Path(), index
+		} else if this.DirIndex {
+			assetfsapi.ServeDirIndex(w, r, this.FS, pth)
+			return
 		} else {
-			httpu.ServeContent(w, r, path.Base(pth), cacheSince, struct {
-				io.ReadCloser
-			}{rc}, nil)
+			this.notFound(w, r, pth, next, notFound)
+			return
 		}
+	}
+
+	var variants []variant
+	if asset != nil && this.Fingerprinter != nil {
+		var rewritten assetfsapi.FileInfo
+		if rewritten, err = this.Fingerprinter.fileInfo(r.Context(), pth, asset); err != nil {
+			this.Error(w, r, pth, http.StatusInternalServerError, err)
+			return
+		}
+		if rewritten != nil {
+			// precompressed variants have the original references
+			asset = rewritten
+		} else {
+			variants = this.variants(r.Context(), pth, asset)
+		}
+	} else {
+		variants = this.variants(r.Context(), pth, asset)
+	}
+	if asset == nil && len(variants) == 0 {
+		this.notFound(w, r, pth, next, notFound)
 		return
 	}
+	if len(variants) > 0 {
+		addVary(w.Header(), "Accept-Encoding")
+	}
 
-	if len(notFound) > 0 && notFound[0] {
-		http.NotFound(w, r)
+	for _, v := range variants {
+		if acceptsEncoding(r, v.encoding) {
+			this.serveFile(w, r, pth, v.info, v.encoding, nil)
+			return
+		}
+	}
+	if asset != nil {
+		this.serveFile(w, r, pth, asset, "", nil)
+		return
+	}
+	for _, v := range variants {
+		if decoder := GetContentDecoder(v.encoding); decoder != nil {
+			this.serveFile(w, r, pth, v.info, "", decoder)
+			return
+		}
+	}
+	this.Error(w, r, pth, http.StatusNotAcceptable)
+}
+
+// unfingerprint returns the file of original path of fingerprinted pth, if the fingerprint matches its
+// digest
+func (this *StaticHandler) unfingerprint(r *http.Request, pth string) assetfsapi.FileInfo {
+	original, fingerprint, ok := ParseFingerprint(pth)
+	if !ok {
+		return nil
+	}
+	info, err := this.FS.AssetInfoC(r.Context(), original)
+	if err != nil || info.IsDir() {
+		return nil
+	}
+	if this.Fingerprinter != nil {
+		if fingerprinted, err := this.Fingerprinter.PathC(r.Context(), original); err != nil || fingerprinted != pth {
+			return nil
+		}
+	} else if etag, err := this.getEtag(info); err != nil || !strings.HasPrefix(etag, `"`+fingerprint) {
+		return nil
+	}
+	return info
+}
+
+// notFound handles the not found pth: it is served by Next handler if set, otherwise by Fallback
+// document if pth has not extension. If notFound, responds with not found status on other cases.
+func (this *StaticHandler) notFound(w http.ResponseWriter, r *http.Request, pth string, next http.Handler, notFound bool) {
+	if next != nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+	if this.Fallback != "" && path.Ext(pth) == "" && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		if info, err := this.FS.AssetInfoC(r.Context(), this.Fallback); err == nil && !info.IsDir() {
+			this.serveFile(w, r, this.Fallback, info, "", nil)
+			return
+		}
+	}
+	if notFound {
+		this.Error(w, r, pth, http.StatusNotFound)
+	}
+}
+
+// Error responds with status code. If ErrorPages, the nearest error page of pth is served.
+func (this *StaticHandler) Error(w http.ResponseWriter, r *http.Request, pth string, code int, err ...error) {
+	h := w.Header()
+	for _, key := range []string{"Etag", "Last-Modified", "Content-Encoding", "Content-Length", "Cache-Control"} {
+		delete(h, key)
+	}
+
+	if this.ErrorPages {
+		if info := this.errorPage(r, pth, code); info != nil {
+			if rc, err := info.Reader(); err == nil {
+				defer rc.Close()
+				var content io.Reader = rc
+				if c, ok := rc.(Compresseder); ok && c.Compressed() {
+					if content, err = gzip.NewReader(rc); err != nil {
+						content = nil
+					}
+				}
+				if content != nil {
+					h.Set("Content-Type", "text/html; charset=utf-8")
+					h.Set("Cache-Control", "no-cache")
+					w.WriteHeader(code)
+					if r.Method != http.MethodHead {
+						io.Copy(w, content)
+					}
+					return
+				}
+			}
+		}
+	}
+
+	msg := http.StatusText(code)
+	if len(err) > 0 && err[0] != nil {
+		msg = err[0].Error()
+	}
+	http.Error(w, msg, code)
+}
+
+// errorPage returns the `<code>.html` file of pth directory or its nearest parent
+func (this *StaticHandler) errorPage(r *http.Request, pth string, code int) assetfsapi.FileInfo {
+	name := strconv.Itoa(code) + ".html"
+	for dir := path.Dir(pth); ; dir = path.Dir(dir) {
+		if info, err := this.FS.AssetInfoC(r.Context(), path.Join(dir, name)); err == nil && !info.IsDir() {
+			return info
+		}
+		if dir == "." || dir == "/" {
+			return nil
+		}
+	}
+}
+
+// serveFile serves the contents of info as pth. If encoding isn't blank, info is the precompressed variant
+// of pth with this content coding. If decoder isn't nil, the contents are decoded with it.
+func (this *StaticHandler) serveFile(w http.ResponseWriter, r *http.Request, pth string, info assetfsapi.FileInfo, encoding string, decoder ContentDecoder) {
+	rc, err := info.Reader()
+	if err != nil {
+		this.Error(w, r, pth, http.StatusInternalServerError, err)
+		return
+	}
+	defer rc.Close()
+
+	etag, err := this.getEtag(info)
+	if err != nil {
+		this.Error(w, r, pth, http.StatusInternalServerError, err)
+		return
+	}
+
+	var (
+		h                 = w.Header()
+		name              = path.Base(pth)
+		modTime           = info.ModTime()
+		content io.Reader = rc
+		size              = info.Size()
+	)
+
+	cmpr, compressed := rc.(Compresseder)
+	if compressed = compressed && cmpr.Compressed(); compressed {
+		addVary(h, "Accept-Encoding")
+		if acceptsEncoding(r, "gzip") {
+			// the stored gzip data is sent as is: its size is unknown
+			encoding, size = "gzip", -1
+		} else if content, err = gzip.NewReader(rc); err != nil {
+			this.Error(w, r, pth, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	switch {
+	case encoding != "":
+		// the entity tag of each representation must be different
+		etag = etag[:len(etag)-1] + "-" + encoding + `"`
+		h.Set("Content-Encoding", encoding)
+		if h.Get("Content-Type") == "" && mime.TypeByExtension(path.Ext(name)) == "" {
+			h.Set("Content-Type", "application/octet-stream")
+		}
+	case decoder != nil:
+		etag = etag[:len(etag)-1] + `-identity"`
+		var dr io.ReadCloser
+		if dr, err = decoder(rc); err != nil {
+			this.Error(w, r, pth, http.StatusInternalServerError, err)
+			return
+		}
+		defer dr.Close()
+		content, size = dr, -1
+	case !compressed && this.isCompressible(name, size):
+		addVary(h, "Accept-Encoding")
+		if encoding = this.compressEncoding(r); encoding != "" {
+			var data []byte
+			if data, err = this.CompressCache.Get(encoding, info, etag, content); err != nil {
+				this.Error(w, r, pth, http.StatusInternalServerError, err)
+				return
+			}
+			etag = etag[:len(etag)-1] + "-" + encoding + `"`
+			h.Set("Content-Encoding", encoding)
+			content, size = bytes.NewReader(data), int64(len(data))
+		}
+	}
+
+	this.serveContent(w, r, pth, etag, modTime, content, size)
+}
+
+// serveContent serves content as pth. If size is unknown, it must be negative.
+func (this *StaticHandler) serveContent(w http.ResponseWriter, r *http.Request, pth, etag string, modTime time.Time, content io.Reader, size int64) {
+	h, name := w.Header(), path.Base(pth)
+	h.Set("Etag", etag)
+	if cc := this.GetCacheControl(pth); cc != "" && h.Get("Cache-Control") == "" {
+		h.Set("Cache-Control", cc)
+	}
+
+	// seekable contents are served by http.ServeContent: it responds to byte-range and multi-range
+	// requests, and copies *os.File to the connection with sendfile.
+	if rs := assetfsapi.ReadSeekerOf(content, size); rs != nil {
+		http.ServeContent(w, r, name, modTime, rs)
+		return
+	}
+
+	if !modTime.IsZero() {
+		h.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	if isNotModified(r, etag, modTime) {
+		delete(h, "Content-Type")
+		delete(h, "Content-Length")
+		delete(h, "Content-Encoding")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if h.Get("Content-Type") == "" {
+		if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+			h.Set("Content-Type", ctype)
+		} else {
+			var buf [512]byte
+			n, _ := io.ReadFull(content, buf[:])
+			h.Set("Content-Type", http.DetectContentType(buf[:n]))
+			content = io.MultiReader(bytes.NewReader(buf[:n]), content)
+		}
+	}
+	if size >= 0 {
+		h.Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		io.Copy(w, content)
+	}
+}
+
+// isNotModified returns if the conditional GET or HEAD request r matches etag or modTime
+func isNotModified(r *http.Request, etag string, modTime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatch(inm, etag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modTime.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modTime.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// etagMatch returns if etag matches one of entity tags of header, using weak comparison
+func etagMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
 	}
+	return false
 }
 
 func HttpStaticHandler(fs assetfsapi.Interface) http.Handler {
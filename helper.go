@@ -4,9 +4,10 @@ import (
 	"compress/gzip"
 	"io"
 	"io/ioutil"
+	"path"
+	"path/filepath"
 )
 
-
 // IsGzip returns true if the string is gzipped data
 func IsGzip(b []byte) bool {
 	if len(b) < 2 {
@@ -53,4 +54,20 @@ func MustDataS(info interface{ Reader() (io.ReadCloser, error) }) (s string) {
 		panic(err)
 	}
 	return
-}
\ No newline at end of file
+}
+
+// cleanPath returns the shortest slash separated path relative to root, or "." if is root.
+func cleanPath(pth string) string {
+	pth = path.Clean("/" + filepath.ToSlash(pth))
+	if pth == "/" {
+		return "."
+	}
+	return pth[1:]
+}
+
+func joinPath(dir, name string) string {
+	if dir == "" || dir == "." {
+		return name
+	}
+	return dir + "/" + name
+}
@@ -0,0 +1,581 @@
+package assetfs
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	iofs "io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	oscommon "github.com/moisespsena-go/os-common"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+	"github.com/moisespsena-go/assetfs/local"
+)
+
+// IOFS exposes an assetfsapi.Interface as io/fs file system. Namespaces are directories and
+// Sub of a namespace returns the IOFS of it.
+type IOFS struct {
+	FS assetfsapi.Interface
+}
+
+// NewIOFS returns the io/fs file system of fs
+func NewIOFS(fs assetfsapi.Interface) *IOFS {
+	return &IOFS{FS: fs}
+}
+
+func (f *IOFS) stat(op, name string) (info iofs.FileInfo, err error) {
+	if !iofs.ValidPath(name) {
+		return nil, &iofs.PathError{Op: op, Path: name, Err: iofs.ErrInvalid}
+	}
+	if name == "." {
+		return &ioRootInfo{}, nil
+	}
+	if ns, err := f.FS.GetNameSpace(name); err == nil {
+		return &NameSpaceFileInfo{assetfsapi.NewCleanedBasicFileInfo(name), ns}, nil
+	}
+	if info, err = f.FS.AssetInfo(name); err == nil {
+		return
+	}
+	if oscommon.IsNotFound(err) || os.IsNotExist(err) {
+		err = iofs.ErrNotExist
+	}
+	return nil, &iofs.PathError{Op: op, Path: name, Err: err}
+}
+
+func (f *IOFS) Stat(name string) (iofs.FileInfo, error) {
+	return f.stat("stat", name)
+}
+
+func (f *IOFS) readDir(op, name string) (entries []iofs.DirEntry, err error) {
+	var info iofs.FileInfo
+	if info, err = f.stat(op, name); err != nil {
+		return
+	}
+	if !info.IsDir() {
+		return nil, &iofs.PathError{Op: op, Path: name, Err: IS_NOT_DIR_ERROR}
+	}
+	set := map[string]bool{}
+	cb := func(info assetfsapi.FileInfo) error {
+		if name := info.Name(); !set[name] {
+			set[name] = true
+			entries = append(entries, ioDirEntry{info})
+		}
+		return nil
+	}
+	if l, ok := f.FS.(ioParentReadDirer); ok {
+		err = l.ReadDirLookUp(name, cb, true, false)
+	} else {
+		err = f.FS.ReadDir(name, cb, false)
+	}
+	if err != nil && !oscommon.IsNotFound(err) {
+		return nil, &iofs.PathError{Op: op, Path: name, Err: err}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+func (f *IOFS) ReadDir(name string) ([]iofs.DirEntry, error) {
+	return f.readDir("readdir", name)
+}
+
+func (f *IOFS) Open(name string) (file iofs.File, err error) {
+	var info iofs.FileInfo
+	if info, err = f.stat("open", name); err != nil {
+		return
+	}
+	if info.IsDir() {
+		return &ioDir{fs: f, name: name, info: info}, nil
+	}
+	var r io.ReadCloser
+	if r, err = info.(assetfsapi.FileInfo).Reader(); err != nil {
+		return nil, &iofs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if c, ok := r.(Compresseder); ok && c.Compressed() {
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(r); err != nil {
+			r.Close()
+			return nil, &iofs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &ioFile{info, gz, r}, nil
+	}
+	if rs, ok := r.(interface {
+		io.ReadSeeker
+		io.ReaderAt
+	}); ok {
+		return &ioSeekFile{ioFile{info, r, r}, rs}, nil
+	}
+	return &ioFile{info, r, r}, nil
+}
+
+func (f *IOFS) Glob(pattern string) ([]string, error) {
+	return iofs.Glob(ioNoGlobFS{f}, pattern)
+}
+
+func (f *IOFS) Sub(dir string) (iofs.FS, error) {
+	if !iofs.ValidPath(dir) {
+		return nil, &iofs.PathError{Op: "sub", Path: dir, Err: iofs.ErrInvalid}
+	}
+	if dir == "." {
+		return f, nil
+	}
+	if ns, err := f.FS.GetNameSpace(dir); err == nil {
+		return NewIOFS(ns), nil
+	}
+	return iofs.Sub(ioNoSubFS{ioNoGlobFS{f}}, dir)
+}
+
+// ioParentReadDirer reads directories into the parents of namespaces too, as the file infos are looked up
+type ioParentReadDirer interface {
+	ReadDirLookUp(dir string, cb assetfsapi.CbWalkInfoFunc, parentLookup, skipDir bool) error
+}
+
+type ioNoGlobFS struct {
+	fs *IOFS
+}
+
+func (f ioNoGlobFS) Open(name string) (iofs.File, error) {
+	return f.fs.Open(name)
+}
+
+func (f ioNoGlobFS) ReadDir(name string) ([]iofs.DirEntry, error) {
+	return f.fs.ReadDir(name)
+}
+
+type ioNoSubFS struct {
+	ioNoGlobFS
+}
+
+func (f ioNoSubFS) Stat(name string) (iofs.FileInfo, error) {
+	return f.fs.Stat(name)
+}
+
+func (f ioNoSubFS) Glob(pattern string) ([]string, error) {
+	return f.fs.Glob(pattern)
+}
+
+// ioEmptyFS is a file system with only the empty root directory
+type ioEmptyFS struct{}
+
+func (ioEmptyFS) Open(name string) (iofs.File, error) {
+	if name == "." {
+		return &ioDir{name: ".", info: ioRootInfo{}, loaded: true}, nil
+	}
+	return nil, &iofs.PathError{Op: "open", Path: name, Err: iofs.ErrNotExist}
+}
+
+type ioRootInfo struct{}
+
+func (ioRootInfo) Name() string       { return "." }
+func (ioRootInfo) Size() int64        { return 0 }
+func (ioRootInfo) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (ioRootInfo) ModTime() time.Time { return time.Time{} }
+func (ioRootInfo) IsDir() bool        { return true }
+func (ioRootInfo) Sys() interface{}   { return nil }
+
+type ioDirEntry struct {
+	info iofs.FileInfo
+}
+
+func (e ioDirEntry) Name() string                 { return e.info.Name() }
+func (e ioDirEntry) IsDir() bool                  { return e.info.IsDir() }
+func (e ioDirEntry) Type() iofs.FileMode          { return e.info.Mode().Type() }
+func (e ioDirEntry) Info() (iofs.FileInfo, error) { return e.info, nil }
+
+type ioFile struct {
+	info   iofs.FileInfo
+	r      io.Reader
+	closer io.Closer
+}
+
+func (f *ioFile) Stat() (iofs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *ioFile) Read(p []byte) (int, error) {
+	return f.r.Read(p)
+}
+
+func (f *ioFile) Close() error {
+	return f.closer.Close()
+}
+
+type ioSeekFile struct {
+	ioFile
+	rs interface {
+		io.ReadSeeker
+		io.ReaderAt
+	}
+}
+
+func (f *ioSeekFile) Seek(offset int64, whence int) (int64, error) {
+	return f.rs.Seek(offset, whence)
+}
+
+func (f *ioSeekFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.rs.ReadAt(p, off)
+}
+
+type ioDir struct {
+	fs      *IOFS
+	name    string
+	info    iofs.FileInfo
+	entries []iofs.DirEntry
+	loaded  bool
+}
+
+func (d *ioDir) Stat() (iofs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *ioDir) Read([]byte) (int, error) {
+	return 0, &iofs.PathError{Op: "read", Path: d.name, Err: IS_DIR_ERROR}
+}
+
+func (d *ioDir) Close() error {
+	return nil
+}
+
+func (d *ioDir) ReadDir(count int) (entries []iofs.DirEntry, err error) {
+	if !d.loaded {
+		if d.entries, err = d.fs.readDir("readdir", d.name); err != nil {
+			return
+		}
+		d.loaded = true
+	}
+	if count <= 0 || count >= len(d.entries) {
+		entries, d.entries = d.entries, nil
+		if count > 0 && len(entries) == 0 {
+			err = io.EOF
+		}
+		return
+	}
+	entries, d.entries = d.entries[:count], d.entries[count:]
+	return
+}
+
+// IOFSFileSystem exposes an io/fs file system, as embed.FS, as assetfsapi.Interface.
+// Namespaces are sub directories.
+type IOFSFileSystem struct {
+	assetfsapi.AssetGetterInterface
+	TraversableInterface
+	local.LocalSourcesAttribute
+
+	FS         iofs.FS
+	parent     *IOFSFileSystem
+	path       string
+	nameSpace  string
+	nameSpaces map[string]*IOFSFileSystem
+	// nameSpacesMu guards nameSpaces
+	nameSpacesMu sync.RWMutex
+	handler      http.Handler
+	plugins      []assetfsapi.Plugin
+}
+
+// NewIOFSFileSystem returns the assetfsapi.Interface of fsys
+func NewIOFSFileSystem(fsys iofs.FS) *IOFSFileSystem {
+	fs := &IOFSFileSystem{FS: fsys}
+	fs.init()
+	return fs
+}
+
+func (fs *IOFSFileSystem) init() {
+	fs.AssetGetterInterface = &AssetGetter{
+		fs: fs,
+		AssetFunc: func(ctx context.Context, name string) (data []byte, err error) {
+			var info assetfsapi.FileInfo
+			if info, err = fs.assetInfo(ctx, name); err != nil {
+				return
+			}
+			return Data(info)
+		},
+		AssetInfoFunc: fs.assetInfo,
+	}
+	fs.TraversableInterface = &Traversable{
+		FS: fs,
+		WalkFunc: func(dir string, cb assetfsapi.CbWalkFunc, mode assetfsapi.WalkMode) error {
+			return fs.walk(dir, func(info assetfsapi.FileInfo) error {
+				return cb(info.Path(), info.IsDir())
+			}, mode)
+		},
+		WalkInfoFunc: fs.walk,
+		ReadDirFunc:  fs.readDir,
+		GlobFunc: func(pattern assetfsapi.GlobPattern, cb func(pth string, isDir bool) error) error {
+			return globInfo(context.Background(), fs, pattern, func(info assetfsapi.FileInfo) error {
+				return cb(info.Path(), info.IsDir())
+			})
+		},
+		GlobInfoFunc: func(pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
+			return globInfo(context.Background(), fs, pattern, cb)
+		},
+		GlobInfoFuncC: func(ctx context.Context, pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
+			return globInfo(ctx, fs, pattern, cb)
+		},
+	}
+}
+
+func (fs *IOFSFileSystem) info(pth string, info iofs.FileInfo) assetfsapi.FileInfo {
+	basic := assetfsapi.NewBasicFileInfo(pth, info.Size(), info.Mode(), info.ModTime(), time.Time{})
+	if ns := fs.nameSpaceAt(pth); ns != nil {
+		return &NameSpaceFileInfo{basic, ns}
+	}
+	finfo := &IOFSFileInfo{basic, fs.FS}
+	if info.IsDir() {
+		return &IOFSDirFileInfo{finfo, fs}
+	}
+	return finfo
+}
+
+func (fs *IOFSFileSystem) assetInfo(ctx context.Context, pth string) (assetfsapi.FileInfo, error) {
+	pth = cleanPath(pth)
+	for _, src := range local.AllSources(fs.LocalSources(), ctx) {
+		if info, err := src.Get(path.Join(fs.path, pth)); err == nil && !info.IsDir() {
+			return &RealFileInfo{basicFileInfo(pth, info), info.Path()}, nil
+		}
+	}
+	info, err := iofs.Stat(fs.FS, pth)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, oscommon.ErrNotFound(pth)
+		}
+		return nil, err
+	}
+	return fs.info(pth, info), nil
+}
+
+func (fs *IOFSFileSystem) readDir(dir string, cb assetfsapi.CbWalkInfoFunc, skipDir bool) error {
+	dir = cleanPath(dir)
+	entries, err := iofs.ReadDir(fs.FS, dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return oscommon.ErrNotFound(dir)
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if skipDir && entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if err = cb(fs.info(joinPath(dir, entry.Name()), info)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *IOFSFileSystem) walk(dir string, cb assetfsapi.CbWalkInfoFunc, mode assetfsapi.WalkMode) error {
+	dir = cleanPath(dir)
+	err := iofs.WalkDir(fs.FS, dir, func(pth string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if pth == dir {
+			return nil
+		}
+		if entry.IsDir() {
+			if fs.nameSpaceAt(pth) != nil && !mode.IsNameSpaces() {
+				return iofs.SkipDir
+			}
+			if !mode.IsDirs() {
+				return nil
+			}
+		} else if !mode.IsFiles() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return cb(fs.info(pth, info))
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// child returns the namespace name of fs, or nil if not exists
+func (fs *IOFSFileSystem) child(name string) *IOFSFileSystem {
+	fs.nameSpacesMu.RLock()
+	defer fs.nameSpacesMu.RUnlock()
+	return fs.nameSpaces[name]
+}
+
+// nameSpaceAt returns the namespace of path pth, which may be nested, or nil if pth isn't a namespace
+func (fs *IOFSFileSystem) nameSpaceAt(pth string) *IOFSFileSystem {
+	if pth = cleanPath(pth); pth == "." {
+		return nil
+	}
+	for _, name := range strings.Split(pth, "/") {
+		if fs = fs.child(name); fs == nil {
+			return nil
+		}
+	}
+	return fs
+}
+
+func (fs *IOFSFileSystem) GetNameSpace(nameSpace string) (assetfsapi.NameSpacedInterface, error) {
+	for _, name := range strings.Split(strings.Trim(nameSpace, "/"), "/") {
+		ns := fs.child(name)
+		if ns == nil {
+			return nil, os.ErrNotExist
+		}
+		fs = ns
+	}
+	return fs, nil
+}
+
+func (fs *IOFSFileSystem) NameSpaces() (items []assetfsapi.NameSpacedInterface) {
+	fs.nameSpacesMu.RLock()
+	defer fs.nameSpacesMu.RUnlock()
+	names := make([]string, 0, len(fs.nameSpaces))
+	for name := range fs.nameSpaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		items = append(items, fs.nameSpaces[name])
+	}
+	return
+}
+
+// NameSpace return namespaced filesystem
+func (fs *IOFSFileSystem) NameSpace(nameSpace string) assetfsapi.NameSpacedInterface {
+	return fs.NameSpaceFS(nameSpace)
+}
+
+// NameSpaceFS return namespaced filesystem. The namespace is the sub directory nameSpace. If nameSpace
+// is not a valid path, the namespace is empty.
+func (fs *IOFSFileSystem) NameSpaceFS(nameSpace string) *IOFSFileSystem {
+	for _, name := range strings.Split(strings.Trim(nameSpace, "/"), "/") {
+		fs = fs.nameSpaceFS(name)
+	}
+	return fs
+}
+
+// nameSpaceFS returns the namespace name of fs, creating it if not exists
+func (fs *IOFSFileSystem) nameSpaceFS(name string) *IOFSFileSystem {
+	fs.nameSpacesMu.Lock()
+	defer fs.nameSpacesMu.Unlock()
+	if ns, ok := fs.nameSpaces[name]; ok {
+		return ns
+	}
+	sub, err := iofs.Sub(fs.FS, name)
+	if err != nil {
+		sub = ioEmptyFS{}
+	}
+	ns := &IOFSFileSystem{FS: sub, parent: fs, path: path.Join(fs.path, name), nameSpace: name, plugins: fs.plugins}
+	ns.SetLocalSources(fs.LocalSources())
+	ns.init()
+	if fs.nameSpaces == nil {
+		fs.nameSpaces = map[string]*IOFSFileSystem{}
+	}
+	fs.nameSpaces[name] = ns
+	return ns
+}
+
+func (fs *IOFSFileSystem) GetName() string {
+	return fs.nameSpace
+}
+
+func (fs *IOFSFileSystem) GetPath() string {
+	return fs.path
+}
+
+func (fs *IOFSFileSystem) GetParent() assetfsapi.Interface {
+	if fs.parent == nil {
+		return nil
+	}
+	return fs.parent
+}
+
+func (fs *IOFSFileSystem) Compile() error {
+	return nil
+}
+
+func (fs *IOFSFileSystem) RegisterPlugin(plugins ...assetfsapi.Plugin) {
+	for _, p := range plugins {
+		p.Init(fs)
+	}
+	fs.plugins = append(fs.plugins, plugins...)
+}
+
+func (fs *IOFSFileSystem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if fs.handler == nil {
+		fs.handler = HttpStaticHandler(fs)
+	}
+	fs.handler.ServeHTTP(w, r)
+}
+
+func (fs *IOFSFileSystem) DumpFiles(cb func(info assetfsapi.FileInfo) error) error {
+	return fs.Dump(cb)
+}
+
+func (fs *IOFSFileSystem) Dump(cb func(info assetfsapi.FileInfo) error, ignore ...func(pth string) bool) error {
+	return fs.walk(".", func(info assetfsapi.FileInfo) error {
+		for _, ignore := range ignore {
+			if ignore(info.Path()) {
+				return nil
+			}
+		}
+		return cb(info)
+	}, assetfsapi.WalkFiles|assetfsapi.WalkNameSpaces)
+}
+
+type IOFSFileInfo struct {
+	assetfsapi.BasicFileInfo
+	fs iofs.FS
+}
+
+func (IOFSFileInfo) Type() assetfsapi.FileType {
+	return assetfsapi.FileTypeNormal
+}
+
+func (f *IOFSFileInfo) GetFileInfo() os.FileInfo {
+	return f.BasicFileInfo
+}
+
+func (f *IOFSFileInfo) RealPath() string {
+	return f.Path()
+}
+
+func (f *IOFSFileInfo) Reader() (io.ReadCloser, error) {
+	return f.fs.Open(f.Path())
+}
+
+func (f *IOFSFileInfo) String() string {
+	return StringifyFileInfo(f)
+}
+
+type IOFSDirFileInfo struct {
+	*IOFSFileInfo
+	dfs *IOFSFileSystem
+}
+
+func (IOFSDirFileInfo) Type() assetfsapi.FileType {
+	return assetfsapi.FileTypeDir
+}
+
+func (d *IOFSDirFileInfo) Reader() (io.ReadCloser, error) {
+	return nil, IS_DIR_ERROR
+}
+
+func (d *IOFSDirFileInfo) ReadDir(cb func(child assetfsapi.FileInfo) error) error {
+	return d.dfs.readDir(d.Path(), cb, false)
+}
+
+func (d *IOFSDirFileInfo) String() string {
+	return StringifyFileInfo(d)
+}
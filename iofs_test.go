@@ -0,0 +1,112 @@
+package assetfs
+
+import (
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+func newTestFS() *AssetFileSystem {
+	fs := NewAssetFileSystem()
+	fs.NameSpaceFS("z").RegisterPath("t/ns")
+	fs.RegisterPath("t/data")
+	fs.RegisterPath("t/data2")
+	return fs
+}
+
+func TestIOFS(t *testing.T) {
+	f := NewIOFS(newTestFS())
+	if err := fstest.TestFS(f, "a.txt", "y.txt", "z/a/x.txt", "z/b/c.txt", "z/sub-ns/nsf.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIOFSSub(t *testing.T) {
+	sub, err := NewIOFS(newTestFS()).Sub("z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(sub, "sub-ns/nsf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if sub, err = iofs.Sub(NewIOFS(newTestFS()), "z/sub-ns"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(sub, "nsf.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIOFSFileSystem(t *testing.T) {
+	fs := NewIOFSFileSystem(os.DirFS("t/data"))
+	if err := fstest.TestFS(NewIOFS(fs), "a.txt", "r.txt", "z/b/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(NewIOFS(fs.NameSpaceFS("z")), "b/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIOFSFileSystemInvalidNameSpace(t *testing.T) {
+	fs := NewIOFSFileSystem(os.DirFS("t/data")).NameSpaceFS("..")
+	if err := fstest.TestFS(NewIOFS(fs)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.AssetInfo("a.txt"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestIOFSFileSystemNestedNameSpace(t *testing.T) {
+	fs := NewIOFSFileSystem(os.DirFS("t/data"))
+	fs.NameSpaceFS("z/b")
+	info, err := fs.AssetInfo("z/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := info.(*NameSpaceFileInfo); !ok {
+		t.Fatalf("z/b: expected namespace, got %T", info)
+	}
+	if err = fs.Walk(".", func(pth string, isDir bool) error {
+		if pth == "z/b/c.txt" {
+			t.Errorf("%s walked", pth)
+		}
+		return nil
+	}, assetfsapi.WalkFiles|assetfsapi.WalkDirs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestIOFSFileSystemConcurrentNameSpaces must be run with `go test -race`
+func TestIOFSFileSystemConcurrentNameSpaces(t *testing.T) {
+	fs := NewIOFSFileSystem(os.DirFS("t/data"))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				fs.NameSpaceFS(fmt.Sprintf("ns%d/%d", i, j))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if err := fs.Walk(".", func(string, bool) error { return nil }, assetfsapi.WalkAll); err != nil {
+					t.Error(err)
+					return
+				}
+				fs.NameSpaces()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(fs.NameSpaces()) != 4 {
+		t.Fatalf("expected 4 namespaces, got %d", len(fs.NameSpaces()))
+	}
+}
@@ -0,0 +1,136 @@
+package assetfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	oscommon "github.com/moisespsena-go/os-common"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+// DirLayer layer of an OS directory. Its whiteouts are cached by directory, which are reloaded when the
+// directory is modified. See ResetWhiteouts.
+type DirLayer struct {
+	Dir string
+
+	whiteouts whiteoutCache
+}
+
+func NewDirLayer(dir string) *DirLayer {
+	return &DirLayer{Dir: filepath.Clean(dir)}
+}
+
+func (l *DirLayer) realPath(pth string) string {
+	return filepath.Join(l.Dir, filepath.FromSlash(cleanPath(pth)))
+}
+
+func (l *DirLayer) AssetInfoC(_ context.Context, pth string) (assetfsapi.FileInfo, error) {
+	realPath := l.realPath(pth)
+	info, err := os.Stat(realPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, oscommon.ErrNotFound(pth)
+		}
+		return nil, err
+	}
+	return newRealFileInfo(cleanPath(pth), realPath, info), nil
+}
+
+func (l *DirLayer) ReadDir(dir string, cb assetfsapi.CbWalkInfoFunc, skipDir bool) error {
+	realPath := l.realPath(dir)
+	infos, err := ioutil.ReadDir(realPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return oscommon.ErrNotFound(dir)
+		}
+		if info, err2 := os.Stat(realPath); err2 == nil && !info.IsDir() {
+			return IS_NOT_DIR_ERROR
+		}
+		return err
+	}
+	dir = cleanPath(dir)
+	for _, info := range infos {
+		if skipDir && info.IsDir() {
+			continue
+		}
+		if err = cb(newRealFileInfo(joinPath(dir, info.Name()), filepath.Join(realPath, info.Name()), info)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *DirLayer) String() string {
+	return l.Dir
+}
+
+func newRealFileInfo(pth, realPath string, info os.FileInfo) assetfsapi.FileInfo {
+	rinfo := &RealFileInfo{basicFileInfo(pth, info), realPath}
+	if info.IsDir() {
+		return &RealDirFileInfo{rinfo}
+	}
+	return rinfo
+}
+
+// isLayerSkipError returns if err reports the path is not provided by the layer
+func isLayerSkipError(err error) bool {
+	return oscommon.IsNotFound(err) || os.IsNotExist(err) || err == IS_NOT_DIR_ERROR
+}
+
+// fileInfoWithPath returns info with virtual path pth
+func fileInfoWithPath(info assetfsapi.FileInfo, pth string) assetfsapi.FileInfo {
+	if info.Path() == pth {
+		return info
+	}
+	switch t := info.(type) {
+	case *RealFileInfo:
+		return &RealFileInfo{assetfsapi.NewBasicFileInfo(pth, t.Size(), t.Mode(), t.ModTime(), changeTime(t.BasicFileInfo)), t.realPath}
+	case *RealDirFileInfo:
+		return &RealDirFileInfo{&RealFileInfo{assetfsapi.NewBasicFileInfo(pth, t.Size(), t.Mode(), t.ModTime(), changeTime(t.RealFileInfo.BasicFileInfo)), t.realPath}}
+	case *NameSpaceFileInfo:
+		return &NameSpaceFileInfo{assetfsapi.NewCleanedBasicFileInfo(pth), t.ns}
+	case assetfsapi.DirFileInfo:
+		return &pathDirFileInfo{&pathFileInfo{info, pth}, t}
+	}
+	return &pathFileInfo{info, pth}
+}
+
+func changeTime(info assetfsapi.BasicFileInfo) (t time.Time) {
+	if ct, ok := info.(assetfsapi.BasicFileInfoWithChangedTime); ok {
+		return ct.ChangeTime()
+	}
+	return
+}
+
+type pathFileInfo struct {
+	assetfsapi.FileInfo
+	path string
+}
+
+func (p *pathFileInfo) Path() string {
+	return p.path
+}
+
+func (p *pathFileInfo) String() string {
+	return StringifyFileInfo(p)
+}
+
+// Unwrap returns the file info with the original path
+func (p *pathFileInfo) Unwrap() assetfsapi.FileInfo {
+	return p.FileInfo
+}
+
+type pathDirFileInfo struct {
+	*pathFileInfo
+	dir assetfsapi.DirFileInfo
+}
+
+func (p *pathDirFileInfo) ReadDir(cb func(child assetfsapi.FileInfo) error) error {
+	return p.dir.ReadDir(func(child assetfsapi.FileInfo) error {
+		return cb(fileInfoWithPath(child, joinPath(p.path, child.Name())))
+	})
+}
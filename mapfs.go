@@ -0,0 +1,544 @@
+package assetfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	oscommon "github.com/moisespsena-go/os-common"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+	"github.com/moisespsena-go/assetfs/local"
+)
+
+// MapFile file stored into MapFileSystem
+type MapFile struct {
+	Data    []byte
+	Mode    os.FileMode
+	ModTime time.Time
+}
+
+type mapNode struct {
+	MapFile
+	name     string
+	ns       *MapFileSystem
+	children map[string]*mapNode
+	names    []string
+}
+
+func (n *mapNode) isDir() bool {
+	return n.Mode.IsDir()
+}
+
+func (n *mapNode) addChild(child *mapNode) {
+	if n.children == nil {
+		n.children = map[string]*mapNode{}
+	}
+	if _, ok := n.children[child.name]; !ok {
+		i := sort.SearchStrings(n.names, child.name)
+		n.names = append(n.names, "")
+		copy(n.names[i+1:], n.names[i:])
+		n.names[i] = child.name
+	}
+	n.children[child.name] = child
+}
+
+func (n *mapNode) removeChild(name string) {
+	delete(n.children, name)
+	if i := sort.SearchStrings(n.names, name); i < len(n.names) && n.names[i] == name {
+		n.names = append(n.names[:i], n.names[i+1:]...)
+	}
+}
+
+// MapFileSystem writable in memory AssetFS. Useful for tests and generated contents.
+type MapFileSystem struct {
+	assetfsapi.AssetGetterInterface
+	TraversableInterface
+	local.LocalSourcesAttribute
+
+	mu        *sync.RWMutex
+	parent    *MapFileSystem
+	root      *mapNode
+	path      string
+	nameSpace string
+	handler   http.Handler
+	plugins   []assetfsapi.Plugin
+}
+
+// NewMapFileSystem create new MapFileSystem with files. The keys of files are slash separated paths. It panics
+// if a file can't be set, as a file inside of other regular file.
+func NewMapFileSystem(files ...map[string]*MapFile) *MapFileSystem {
+	fs := &MapFileSystem{mu: &sync.RWMutex{}, root: &mapNode{MapFile: MapFile{Mode: os.ModeDir | 0755}}}
+	fs.root.ns = fs
+	fs.init()
+	for _, files := range files {
+		for pth, f := range files {
+			if err := fs.Set(pth, f); err != nil {
+				panic(fmt.Errorf("assetfs: NewMapFileSystem: set %q: %v", pth, err))
+			}
+		}
+	}
+	return fs
+}
+
+func (fs *MapFileSystem) init() {
+	fs.AssetGetterInterface = &AssetGetter{
+		fs: fs,
+		AssetFunc: func(ctx context.Context, name string) (data []byte, err error) {
+			var info assetfsapi.FileInfo
+			if info, err = fs.assetInfo(ctx, name); err != nil {
+				return
+			}
+			return Data(info)
+		},
+		AssetInfoFunc: fs.assetInfo,
+	}
+	fs.TraversableInterface = &Traversable{
+		FS: fs,
+		WalkFunc: func(dir string, cb assetfsapi.CbWalkFunc, mode assetfsapi.WalkMode) error {
+			return fs.walk(dir, func(info assetfsapi.FileInfo) error {
+				return cb(info.Path(), info.IsDir())
+			}, mode)
+		},
+		WalkInfoFunc: fs.walk,
+		ReadDirFunc:  fs.readDir,
+		GlobFunc: func(pattern assetfsapi.GlobPattern, cb func(pth string, isDir bool) error) error {
+			return globInfo(context.Background(), fs, pattern, func(info assetfsapi.FileInfo) error {
+				return cb(info.Path(), info.IsDir())
+			})
+		},
+		GlobInfoFunc: func(pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
+			return globInfo(context.Background(), fs, pattern, cb)
+		},
+		GlobInfoFuncC: func(ctx context.Context, pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
+			return globInfo(ctx, fs, pattern, cb)
+		},
+	}
+}
+
+// Set set file pth with a copy of file data. If file mode is a directory, creates the directory. Parent
+// directories are created if not exists. Namespaces can't be replaced by files.
+func (fs *MapFileSystem) Set(pth string, file *MapFile) (err error) {
+	f := *file
+	f.Data = append([]byte{}, file.Data...)
+	if f.ModTime.IsZero() {
+		f.ModTime = time.Now()
+	}
+	if f.Mode.IsDir() {
+		_, err = fs.MkdirAll(pth, f.Mode.Perm())
+		return
+	}
+	if f.Mode&os.ModeType == 0 && f.Mode.Perm() == 0 {
+		f.Mode |= 0644
+	}
+	pth = cleanPath(pth)
+	if pth == "." {
+		return IS_DIR_ERROR
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	var dir *mapNode
+	if dir, err = fs.mkdirAll(path.Dir(pth), 0755, f.ModTime); err != nil {
+		return
+	}
+	name := path.Base(pth)
+	if old := dir.children[name]; old != nil && old.ns != nil {
+		return IS_NS_ERROR
+	} else if old != nil && old.isDir() {
+		return IS_DIR_ERROR
+	}
+	dir.addChild(&mapNode{MapFile: f, name: name})
+	return
+}
+
+// WriteFile writes data to file pth, creating it with perm if not exists
+func (fs *MapFileSystem) WriteFile(pth string, data []byte, perm os.FileMode) error {
+	return fs.Set(pth, &MapFile{Data: data, Mode: perm.Perm()})
+}
+
+// MkdirAll creates the directory pth and all parents if not exists
+func (fs *MapFileSystem) MkdirAll(pth string, perm os.FileMode) (info assetfsapi.FileInfo, err error) {
+	pth = cleanPath(pth)
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	var node *mapNode
+	if node, err = fs.mkdirAll(pth, perm, time.Now()); err != nil {
+		return
+	}
+	return fs.info(pth, node), nil
+}
+
+func (fs *MapFileSystem) mkdirAll(pth string, perm os.FileMode, modTime time.Time) (node *mapNode, err error) {
+	node = fs.root
+	if pth == "." {
+		return
+	}
+	for _, name := range strings.Split(pth, "/") {
+		child := node.children[name]
+		if child == nil {
+			child = &mapNode{MapFile: MapFile{Mode: os.ModeDir | perm.Perm(), ModTime: modTime}, name: name}
+			node.addChild(child)
+			if node.ModTime.Before(modTime) {
+				node.ModTime = modTime
+			}
+		} else if !child.isDir() {
+			return nil, IS_NOT_DIR_ERROR
+		}
+		node = child
+	}
+	return
+}
+
+// Remove removes file or directory pth and all its contents. Namespaces can't be removed.
+func (fs *MapFileSystem) Remove(pth string) error {
+	pth = cleanPath(pth)
+	if pth == "." {
+		return IS_NS_ERROR
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	dir := fs.get(path.Dir(pth))
+	if dir == nil || dir.children[path.Base(pth)] == nil {
+		return oscommon.ErrNotFound(pth)
+	}
+	if dir.children[path.Base(pth)].ns != nil {
+		return IS_NS_ERROR
+	}
+	dir.removeChild(path.Base(pth))
+	dir.ModTime = time.Now()
+	return nil
+}
+
+func (fs *MapFileSystem) get(pth string) *mapNode {
+	node := fs.root
+	if pth = cleanPath(pth); pth == "." {
+		return node
+	}
+	for _, name := range strings.Split(pth, "/") {
+		if node = node.children[name]; node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+func (fs *MapFileSystem) info(pth string, node *mapNode) assetfsapi.FileInfo {
+	basic := assetfsapi.NewBasicFileInfo(pth, int64(len(node.Data)), node.Mode, node.ModTime, time.Time{})
+	if node.ns != nil && node != fs.root {
+		return &NameSpaceFileInfo{basic, node.ns}
+	}
+	info := &MapFileInfo{basic, fs, node}
+	if node.isDir() {
+		return &MapDirFileInfo{info}
+	}
+	return info
+}
+
+func (fs *MapFileSystem) assetInfo(ctx context.Context, pth string) (assetfsapi.FileInfo, error) {
+	pth = cleanPath(pth)
+	for _, src := range local.AllSources(fs.LocalSources(), ctx) {
+		if info, err := src.Get(path.Join(fs.path, pth)); err == nil && !info.IsDir() {
+			return &RealFileInfo{basicFileInfo(pth, info), info.Path()}, nil
+		}
+	}
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	if node := fs.get(pth); node != nil {
+		return fs.info(pth, node), nil
+	}
+	return nil, oscommon.ErrNotFound(pth)
+}
+
+// children returns the infos of dir children. The callbacks are called without lock, so it can changes the
+// file system.
+func (fs *MapFileSystem) children(dir string) (infos []assetfsapi.FileInfo, err error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	node := fs.get(dir)
+	if node == nil {
+		return nil, oscommon.ErrNotFound(dir)
+	}
+	if !node.isDir() {
+		return nil, IS_NOT_DIR_ERROR
+	}
+	infos = make([]assetfsapi.FileInfo, len(node.names))
+	for i, name := range node.names {
+		infos[i] = fs.info(joinPath(dir, name), node.children[name])
+	}
+	return
+}
+
+func (fs *MapFileSystem) readDir(dir string, cb assetfsapi.CbWalkInfoFunc, skipDir bool) (err error) {
+	infos, err := fs.children(cleanPath(dir))
+	if err != nil {
+		return
+	}
+	for _, info := range infos {
+		if skipDir && info.IsDir() {
+			continue
+		}
+		if err = cb(info); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (fs *MapFileSystem) walk(dir string, cb assetfsapi.CbWalkInfoFunc, mode assetfsapi.WalkMode) (err error) {
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		infos, err := fs.children(dir)
+		if err != nil {
+			return err
+		}
+		if mode.IsReverse() {
+			for i, j := 0, len(infos)-1; i < j; i, j = i+1, j-1 {
+				infos[i], infos[j] = infos[j], infos[i]
+			}
+		}
+		for _, info := range infos {
+			if !info.IsDir() {
+				if mode.IsFiles() {
+					if err := cb(info); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if info.Type().IsNameSpace() && !mode.IsNameSpaces() {
+				continue
+			}
+			if mode.IsDirs() {
+				if err := cb(info); err != nil {
+					if err == filepath.SkipDir {
+						continue
+					}
+					return err
+				}
+			}
+			if err := walk(info.Path()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(cleanPath(dir))
+}
+
+func (fs *MapFileSystem) GetNameSpace(nameSpace string) (assetfsapi.NameSpacedInterface, error) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	node := fs.get(nameSpace)
+	if node == nil || node.ns == nil || node == fs.root {
+		return nil, os.ErrNotExist
+	}
+	return node.ns, nil
+}
+
+func (fs *MapFileSystem) NameSpaces() (items []assetfsapi.NameSpacedInterface) {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	for _, name := range fs.root.names {
+		if child := fs.root.children[name]; child.ns != nil {
+			items = append(items, child.ns)
+		}
+	}
+	return
+}
+
+// NameSpace return namespaced filesystem
+func (fs *MapFileSystem) NameSpace(nameSpace string) assetfsapi.NameSpacedInterface {
+	return fs.NameSpaceFS(nameSpace)
+}
+
+// NameSpaceFS return namespaced filesystem. A regular file with the same name is replaced by the namespace
+// directory, as namespaces takes precedence over the files of same path in AssetFileSystem.
+func (fs *MapFileSystem) NameSpaceFS(nameSpace string) *MapFileSystem {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, name := range strings.Split(strings.Trim(nameSpace, "/"), "/") {
+		node := fs.root.children[name]
+		if node == nil || !node.isDir() {
+			node = &mapNode{MapFile: MapFile{Mode: os.ModeDir | 0755, ModTime: time.Now()}, name: name}
+			fs.root.addChild(node)
+		}
+		if node.ns == nil {
+			node.ns = &MapFileSystem{
+				mu:        fs.mu,
+				parent:    fs,
+				root:      node,
+				path:      path.Join(fs.path, name),
+				nameSpace: name,
+				plugins:   fs.plugins,
+			}
+			node.ns.SetLocalSources(fs.LocalSources())
+			node.ns.init()
+		}
+		fs = node.ns
+	}
+	return fs
+}
+
+func (fs *MapFileSystem) GetName() string {
+	return fs.nameSpace
+}
+
+func (fs *MapFileSystem) GetPath() string {
+	return fs.path
+}
+
+func (fs *MapFileSystem) GetParent() assetfsapi.Interface {
+	if fs.parent == nil {
+		return nil
+	}
+	return fs.parent
+}
+
+// Compile does nothing: MapFileSystem contents are already in memory
+func (fs *MapFileSystem) Compile() error {
+	return nil
+}
+
+func (fs *MapFileSystem) RegisterPlugin(plugins ...assetfsapi.Plugin) {
+	for _, p := range plugins {
+		p.Init(fs)
+	}
+	fs.plugins = append(fs.plugins, plugins...)
+}
+
+func (fs *MapFileSystem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if fs.handler == nil {
+		fs.handler = HttpStaticHandler(fs)
+	}
+	fs.handler.ServeHTTP(w, r)
+}
+
+func (fs *MapFileSystem) DumpFiles(cb func(info assetfsapi.FileInfo) error) error {
+	return fs.Dump(cb)
+}
+
+func (fs *MapFileSystem) Dump(cb func(info assetfsapi.FileInfo) error, ignore ...func(pth string) bool) error {
+	return fs.walk(".", func(info assetfsapi.FileInfo) error {
+		for _, ignore := range ignore {
+			if ignore(info.Path()) {
+				return nil
+			}
+		}
+		return cb(info)
+	}, assetfsapi.WalkFiles|assetfsapi.WalkNameSpaces)
+}
+
+type MapFileInfo struct {
+	assetfsapi.BasicFileInfo
+	fs   *MapFileSystem
+	node *mapNode
+}
+
+func (MapFileInfo) Type() assetfsapi.FileType {
+	return assetfsapi.FileTypeNormal
+}
+
+func (m *MapFileInfo) GetFileInfo() os.FileInfo {
+	return m.BasicFileInfo
+}
+
+func (m *MapFileInfo) RealPath() string {
+	return "mem:" + path.Join(m.fs.path, m.Path())
+}
+
+func (m *MapFileInfo) Reader() (io.ReadCloser, error) {
+	m.fs.mu.RLock()
+	defer m.fs.mu.RUnlock()
+	return mapReader{bytes.NewReader(m.node.Data)}, nil
+}
+
+// Writer returns a writer that replaces the file contents on close
+func (m *MapFileInfo) Writer() (io.WriteCloser, error) {
+	return &mapWriter{fs: m.fs, node: m.node}, nil
+}
+
+// Appender returns a writer that appends to the file contents on close
+func (m *MapFileInfo) Appender() (io.WriteCloser, error) {
+	w := &mapWriter{fs: m.fs, node: m.node}
+	m.fs.mu.RLock()
+	w.buf.Write(m.node.Data)
+	m.fs.mu.RUnlock()
+	return w, nil
+}
+
+func (m *MapFileInfo) String() string {
+	return StringifyFileInfo(m)
+}
+
+type MapDirFileInfo struct {
+	*MapFileInfo
+}
+
+func (MapDirFileInfo) Type() assetfsapi.FileType {
+	return assetfsapi.FileTypeDir
+}
+
+func (m *MapDirFileInfo) Reader() (io.ReadCloser, error) {
+	return nil, IS_DIR_ERROR
+}
+
+func (m *MapDirFileInfo) Writer() (io.WriteCloser, error) {
+	return nil, IS_DIR_ERROR
+}
+
+func (m *MapDirFileInfo) Appender() (io.WriteCloser, error) {
+	return nil, IS_DIR_ERROR
+}
+
+func (m *MapDirFileInfo) ReadDir(cb func(child assetfsapi.FileInfo) error) error {
+	return m.fs.readDir(m.Path(), cb, false)
+}
+
+func (m *MapDirFileInfo) String() string {
+	return StringifyFileInfo(m)
+}
+
+type mapReader struct {
+	*bytes.Reader
+}
+
+func (mapReader) Close() error {
+	return nil
+}
+
+// mapWriter buffers the written data and stores it into node on close. Readers opened before close are not
+// affected.
+type mapWriter struct {
+	fs   *MapFileSystem
+	node *mapNode
+	buf  bytes.Buffer
+}
+
+func (w *mapWriter) Write(p []byte) (int, error) {
+	if w.node == nil {
+		return 0, os.ErrClosed
+	}
+	return w.buf.Write(p)
+}
+
+func (w *mapWriter) Close() error {
+	if w.node == nil {
+		return os.ErrClosed
+	}
+	w.fs.mu.Lock()
+	w.node.Data, w.node.ModTime = w.buf.Bytes(), time.Now()
+	w.fs.mu.Unlock()
+	w.node = nil
+	return nil
+}
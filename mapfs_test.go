@@ -0,0 +1,107 @@
+package assetfs
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+func newTestMapFS() *MapFileSystem {
+	fs := NewMapFileSystem(map[string]*MapFile{
+		"a.txt":   {Data: []byte("a")},
+		"d/b.txt": {Data: []byte("b")},
+		"e":       {Mode: os.ModeDir | 0755},
+	})
+	fs.NameSpaceFS("n/s").WriteFile("x/y.txt", []byte("y"), 0644)
+	return fs
+}
+
+func TestMapFileSystem(t *testing.T) {
+	fs := newTestMapFS()
+	if err := fstest.TestFS(NewIOFS(fs), "a.txt", "d/b.txt", "n/s/x/y.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(NewIOFS(fs.NameSpaceFS("n")), "s/x/y.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMapFileSystemSet(t *testing.T) {
+	data := []byte("a")
+	fs := NewMapFileSystem()
+	if err := fs.Set("a.txt", &MapFile{Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	data[0] = 'b'
+	if s := MustDataS(fs.MustAssetInfo("a.txt")); s != "a" {
+		t.Fatalf("a.txt: expected %q, got %q", "a", s)
+	}
+	if err := fs.Set("a.txt/b.txt", &MapFile{}); err != IS_NOT_DIR_ERROR {
+		t.Fatalf("file into file: %v", err)
+	}
+	fs.NameSpaceFS("ns")
+	if err := fs.Set("ns", &MapFile{}); err != IS_NS_ERROR {
+		t.Fatalf("file replaces namespace: %v", err)
+	}
+
+	fs.NameSpaceFS("a.txt")
+	info, err := fs.AssetInfo("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Type().IsNameSpace() {
+		t.Fatalf("a.txt: expected namespace, got %v", info)
+	}
+}
+
+func TestMapFileSystemWalk(t *testing.T) {
+	fs := newTestMapFS()
+	var names []string
+	if err := fs.ReadDir(".", func(info assetfsapi.FileInfo) error {
+		names = append(names, info.Path())
+		return nil
+	}, false); err != nil {
+		t.Fatal(err)
+	}
+	if !equalStrings(names, []string{"a.txt", "d", "e", "n"}) {
+		t.Fatalf("ReadDir: %v", names)
+	}
+
+	for mode, expected := range map[assetfsapi.WalkMode][]string{
+		assetfsapi.WalkFiles:                             {"a.txt", "d/b.txt"},
+		assetfsapi.WalkFiles | assetfsapi.WalkNameSpaces: {"a.txt", "d/b.txt", "n/s/x/y.txt"},
+	} {
+		names = nil
+		if err := fs.Walk(".", func(pth string, isDir bool) error {
+			names = append(names, pth)
+			return nil
+		}, mode); err != nil {
+			t.Fatal(err)
+		}
+		if !equalStrings(names, expected) {
+			t.Errorf("Walk %v: %v", mode, names)
+		}
+	}
+
+	ns, err := fs.GetNameSpace("n/s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.(*MapFileSystem) != fs.NameSpaceFS("n/s") || len(fs.NameSpaces()) != 1 {
+		t.Fatal("bad namespaces")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
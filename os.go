@@ -17,6 +17,9 @@ var (
 	now          = time.Now()
 	IS_DIR_ERROR = errors.New("Is directory.")
 	IS_NS_ERROR  = errors.New("Is name space.")
+
+	IS_NOT_DIR_ERROR = errors.New("Is not directory.")
+	READ_ONLY_ERROR  = errors.New("Is read only.")
 )
 
 type RealFileInfo struct {
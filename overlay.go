@@ -0,0 +1,220 @@
+package assetfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+	"github.com/moisespsena-go/assetfs/local"
+)
+
+// layerLookUp options of layers look up
+type layerLookUp struct {
+	// nameSpaces look up into layers of namespaces of path
+	nameSpaces bool
+	// parents look up into layers of parents, using namespace name as path prefix
+	parents bool
+	// reverse iterates layers of each file system from low to high priority
+	reverse bool
+}
+
+// nameSpaceOf returns the deepest namespace of pth and the path relative to it
+func (fs *AssetFileSystem) nameSpaceOf(pth string) (*AssetFileSystem, string) {
+	for pth != "." {
+		parts := strings.SplitN(pth, "/", 2)
+		ns, ok := fs.load().nameSpaces.Get(parts[0])
+		if !ok {
+			break
+		}
+		fs, pth = ns, "."
+		if len(parts) == 2 {
+			pth = parts[1]
+		}
+	}
+	return fs, pth
+}
+
+// eachLayer calls cb with each layer that can provide pth and the path into it, from high to low priority:
+// layers of the namespace of pth first, then layers of parents.
+func (fs *AssetFileSystem) eachLayer(pth string, lookUp layerLookUp, cb func(layer assetfsapi.Layer, pth string) error) (err error) {
+	return fs.eachLayerOf(pth, lookUp, func(_ *AssetFileSystem, layer assetfsapi.Layer, pth string) error {
+		return cb(layer, pth)
+	})
+}
+
+// eachLayerOf is like eachLayer, but calls cb with the file system which registers the layer too
+func (fs *AssetFileSystem) eachLayerOf(pth string, lookUp layerLookUp, cb func(owner *AssetFileSystem, layer assetfsapi.Layer, pth string) error) (err error) {
+	cur, pth := fs, cleanPath(pth)
+	if lookUp.nameSpaces {
+		cur, pth = fs.nameSpaceOf(pth)
+	}
+	for {
+		layers := cur.load().layers
+		if lookUp.reverse {
+			for i := len(layers); i > 0; i-- {
+				if err = cb(cur, layers[i-1], pth); err != nil {
+					return
+				}
+			}
+		} else {
+			for _, layer := range layers {
+				if err = cb(cur, layer, pth); err != nil {
+					return
+				}
+			}
+		}
+		if cur.parent == nil || (cur == fs && !lookUp.parents) {
+			return
+		}
+		pth = path.Join(cur.nameSpace, pth)
+		cur = cur.parent.(*AssetFileSystem)
+	}
+}
+
+// readDirLookUp calls cb with each entry of dir, merged from namespaces, local sources of ctx and all layers.
+// Entries of high priority layers hides entries with same name of low priority layers. Whiteout files are
+// not listed: they hide entries of low priority layers.
+func (fs *AssetFileSystem) readDirLookUp(ctx context.Context, dir string, cb assetfsapi.CbWalkInfoFunc, lookUp layerLookUp, skipDir bool) (err error) {
+	dir = cleanPath(dir)
+	set := map[string]bool{}
+	emit := func(info assetfsapi.FileInfo) error {
+		name := info.Name()
+		if set[name] {
+			return nil
+		}
+		set[name] = true
+		if skipDir && info.IsDir() {
+			return nil
+		}
+		return cb(fileInfoWithPath(info, joinPath(dir, name)))
+	}
+
+	// hidden if dir of low priority layers is hidden by whiteouts
+	var hidden bool
+	// read calls read with the entries callback of a layer. Its whiteouts hides entries of next layers.
+	read := func(read func(cb assetfsapi.CbWalkInfoFunc) error, whitedOut func() bool) error {
+		var (
+			whiteouts []string
+			opaque    bool
+		)
+		if err := read(func(info assetfsapi.FileInfo) error {
+			if name := info.Name(); name == WhiteoutOpaque {
+				opaque = true
+			} else if IsWhiteout(name) {
+				whiteouts = append(whiteouts, strings.TrimPrefix(name, WhiteoutPrefix))
+			} else {
+				return emit(info)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, name := range whiteouts {
+			set[name] = true
+		}
+		hidden = opaque || whitedOut()
+		return nil
+	}
+
+	if ns, pth := fs.nameSpaceOf(dir); pth == "." {
+		if err = ns.load().nameSpaces.Each(func(name string, child *AssetFileSystem) error {
+			return emit(&NameSpaceFileInfo{assetfsapi.NewCleanedBasicFileInfo(name), child})
+		}); err != nil {
+			return
+		}
+	}
+
+	for _, src := range local.AllSources(fs.localSourcesRegister(), ctx) {
+		src := src
+		if err = read(func(cb assetfsapi.CbWalkInfoFunc) error {
+			return readSourceDir(src, fs.rootPath(dir), func(name, realPath string, info os.FileInfo) error {
+				return cb(newRealFileInfo(joinPath(dir, name), realPath, info))
+			})
+		}, func() bool {
+			return sourceWhitedOut(src, fs.rootPath(dir))
+		}); err != nil || hidden {
+			return
+		}
+	}
+
+	err = fs.eachLayer(dir, lookUp, func(layer assetfsapi.Layer, pth string) error {
+		if err := read(func(cb assetfsapi.CbWalkInfoFunc) error {
+			if err := layer.ReadDir(pth, cb, false); err != nil && !isLayerSkipError(err) {
+				return err
+			}
+			return nil
+		}, func() bool {
+			return layerWhitedOut(ctx, layer, pth)
+		}); err != nil {
+			return err
+		}
+		if hidden {
+			return io.EOF
+		}
+		return nil
+	})
+	if err == io.EOF {
+		return nil
+	}
+	return
+}
+
+// rootPath returns pth relative to the root file system
+func (fs *AssetFileSystem) rootPath(pth string) string {
+	return path.Join(filepath.ToSlash(fs.path), cleanPath(pth))
+}
+
+// localSourcesRegister returns the local sources register of fs or of the nearest parent
+func (fs *AssetFileSystem) localSourcesRegister() assetfsapi.LocalSourceRegister {
+	for {
+		if r := fs.LocalSources(); r != nil || fs.parent == nil {
+			return r
+		}
+		fs = fs.parent.(*AssetFileSystem)
+	}
+}
+
+// readSourceDir calls cb with each entry of directory pth of local source src, sorted by name. If the
+// directory does not exists, does nothing.
+func readSourceDir(src assetfsapi.LocalSource, pth string, cb func(name, realPath string, info os.FileInfo) error) error {
+	if src.Dir() == "" {
+		return nil
+	}
+	dir := filepath.Join(src.Dir(), local.FilePath(pth))
+	f, err := os.Open(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("source «%T» %s read dir %q failed: %v", src, src, pth, err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || !info.IsDir() {
+		return nil
+	}
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return fmt.Errorf("source «%T» %s read dir %q failed: %v", src, src, pth, err)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		realPath := filepath.Join(dir, name)
+		info, err := os.Stat(realPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("source «%T» %s get info for %q failed: %v", src, src, path.Join(pth, name), err)
+		}
+		if err = cb(name, realPath, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
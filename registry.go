@@ -0,0 +1,39 @@
+package assetfs
+
+import (
+	"os"
+	"sync"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+var (
+	fileSystems   = map[string]assetfsapi.Interface{}
+	fileSystemsMu sync.RWMutex
+)
+
+// RegisterFileSystem register fs as name. Packages generated by assetfs-gen register it self on init, so
+// switching between disk and compiled file systems changes only the package import.
+func RegisterFileSystem(name string, fs assetfsapi.Interface) {
+	fileSystemsMu.Lock()
+	defer fileSystemsMu.Unlock()
+	fileSystems[name] = fs
+}
+
+// GetFileSystem returns the file system registered as name
+func GetFileSystem(name string) (assetfsapi.Interface, error) {
+	fileSystemsMu.RLock()
+	defer fileSystemsMu.RUnlock()
+	if fs, ok := fileSystems[name]; ok {
+		return fs, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func MustGetFileSystem(name string) assetfsapi.Interface {
+	fs, err := GetFileSystem(name)
+	if err != nil {
+		panic(&os.PathError{Op: "get file system", Path: name, Err: err})
+	}
+	return fs
+}
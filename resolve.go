@@ -0,0 +1,174 @@
+package assetfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+	"github.com/moisespsena-go/assetfs/local"
+)
+
+// Candidate a provider of a path. It is one of a local source, a namespace or a layer.
+type Candidate struct {
+	// LocalSource the local source which provides the path
+	LocalSource assetfsapi.LocalSource
+	// LocalSourceName the name of LocalSource into the local sources register. It is empty for sources
+	// of context set by local.SetSources.
+	LocalSourceName string
+	// Layer the layer which provides the path
+	Layer assetfsapi.Layer
+	// Owner the path of file system which registers Layer, or the path of the namespace
+	Owner string
+	// RealPath the real path of Info
+	RealPath string
+	Info     assetfsapi.FileInfo
+	// Shadowed if a candidate with higher priority provides the path
+	Shadowed bool
+}
+
+func (c *Candidate) String() (s string) {
+	switch {
+	case c.LocalSource != nil:
+		s = fmt.Sprintf("local source %q %s", c.LocalSourceName, c.RealPath)
+	case c.Layer != nil:
+		s = fmt.Sprintf("layer %s of %q: %s", layerName(c.Layer), c.Owner, c.RealPath)
+	default:
+		s = fmt.Sprintf("namespace %q", c.Owner)
+	}
+	if c.Shadowed {
+		s += " (shadowed)"
+	}
+	return
+}
+
+func layerName(layer assetfsapi.Layer) string {
+	if s, ok := layer.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", layer)
+}
+
+// Resolution the candidates of a path
+type Resolution struct {
+	Path       string
+	Candidates []*Candidate
+}
+
+// Winner returns the candidate which provides the path
+func (r *Resolution) Winner() *Candidate {
+	if len(r.Candidates) == 0 {
+		return nil
+	}
+	return r.Candidates[0]
+}
+
+func (r *Resolution) String() string {
+	s := r.Path + ":"
+	for _, c := range r.Candidates {
+		s += "\n  " + c.String()
+	}
+	return s
+}
+
+// Resolve returns all candidates of pth in priority order, the same used by AssetInfoC: local sources of ctx,
+// the namespace, then the layers. All candidates but the first are shadowed. The look up stops at the first
+// local source or layer which whiteouts pth. A nil ctx is the same as context.Background().
+func (fs *AssetFileSystem) Resolve(ctx context.Context, pth string) (candidates []*Candidate, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	pth = cleanPath(pth)
+	add := func(c *Candidate) {
+		c.Shadowed = len(candidates) > 0
+		candidates = append(candidates, c)
+	}
+
+	var (
+		register = fs.localSourcesRegister()
+		sources  = local.GetSources(ctx)
+		names    = make([]string, len(sources))
+	)
+	if register != nil {
+		for _, name := range local.GetNames(ctx) {
+			if src := register.Get(name); src != nil {
+				sources = append(sources, src)
+				names = append(names, name)
+			}
+		}
+	}
+	for i, src := range sources {
+		srcInfo, err := src.Get(fs.rootPath(pth))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("source «%T» %s get info for %q failed: %v", src, src, pth, err)
+			}
+			if sourceWhitedOut(src, fs.rootPath(pth)) {
+				return candidates, nil
+			}
+			continue
+		}
+		add(&Candidate{
+			LocalSource:     src,
+			LocalSourceName: names[i],
+			RealPath:        srcInfo.Path(),
+			Info:            newRealFileInfo(pth, srcInfo.Path(), srcInfo),
+		})
+	}
+
+	if ns, nsPth := fs.nameSpaceOf(pth); nsPth == "." && ns != fs {
+		add(&Candidate{
+			Owner:    ns.rootPath("."),
+			RealPath: ns.GetPath(),
+			Info:     &NameSpaceFileInfo{assetfsapi.NewCleanedBasicFileInfo(pth), ns},
+		})
+	}
+
+	err = fs.eachLayerOf(pth, layerLookUp{nameSpaces: true, parents: true}, func(owner *AssetFileSystem, layer assetfsapi.Layer, lpth string) error {
+		info, err := layer.AssetInfoC(ctx, lpth)
+		if err != nil {
+			if isLayerSkipError(err) {
+				if layerWhitedOut(ctx, layer, lpth) {
+					return io.EOF
+				}
+				return nil
+			}
+			return err
+		}
+		add(&Candidate{
+			Layer:    layer,
+			Owner:    owner.rootPath("."),
+			RealPath: info.RealPath(),
+			Info:     fileInfoWithPath(info, pth),
+		})
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// Overridden returns the resolution of all files provided by more than one candidate, sorted by path
+func (fs *AssetFileSystem) Overridden(ctx context.Context) (result []*Resolution, err error) {
+	var paths []string
+	if err = fs.WalkInfoC(ctx, ".", func(info assetfsapi.FileInfo) error {
+		paths = append(paths, info.Path())
+		return nil
+	}, assetfsapi.WalkFiles|assetfsapi.WalkNameSpaces|assetfsapi.WalkParentLookUp); err != nil {
+		return
+	}
+	sort.Strings(paths)
+	for _, pth := range paths {
+		var candidates []*Candidate
+		if candidates, err = fs.Resolve(ctx, pth); err != nil {
+			return nil, err
+		}
+		if len(candidates) > 1 {
+			result = append(result, &Resolution{pth, candidates})
+		}
+	}
+	return
+}
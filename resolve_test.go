@@ -0,0 +1,14 @@
+package assetfs
+
+import "testing"
+
+func TestResolveNilContext(t *testing.T) {
+	fs := newTestFS()
+	candidates, err := fs.Resolve(nil, "z/a/x.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) == 0 || candidates[0].Shadowed {
+		t.Fatalf("bad candidates: %v", candidates)
+	}
+}
@@ -0,0 +1,118 @@
+package assetfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+// RefFinder returns the start and end offsets of references to other assets in data, in ascending order
+type RefFinder func(data []byte) [][2]int
+
+var (
+	cssRefRegexp  = regexp.MustCompile(`url\(\s*(?:"([^"]*)"|'([^']*)'|([^'"\s)]+))\s*\)|@import\s+(?:"([^"]*)"|'([^']*)')`)
+	htmlRefRegexp = regexp.MustCompile(`(?i)\b(?:src|href)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+	// FindCSSRefs finds `url(...)` and `@import` references
+	FindCSSRefs RefFinder = func(data []byte) [][2]int {
+		return findRefs(data, cssRefRegexp)
+	}
+	// FindHTMLRefs finds `src` and `href` attributes, and `url(...)` references of inline styles
+	FindHTMLRefs RefFinder = func(data []byte) [][2]int {
+		return findRefs(data, htmlRefRegexp, cssRefRegexp)
+	}
+
+	// RefFinders finders of references by file extension, used by Fingerprinter to rewrite references
+	RefFinders = map[string]RefFinder{
+		".css":  FindCSSRefs,
+		".html": FindHTMLRefs,
+		".htm":  FindHTMLRefs,
+	}
+)
+
+// findRefs returns the offsets of first matched group of each match of regexps, without overlaps
+func findRefs(data []byte, regexps ...*regexp.Regexp) (refs [][2]int) {
+	for _, re := range regexps {
+		for _, m := range re.FindAllSubmatchIndex(data, -1) {
+			for i := 2; i < len(m); i += 2 {
+				if m[i] >= 0 {
+					refs = append(refs, [2]int{m[i], m[i+1]})
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i][0] < refs[j][0]
+	})
+	var end int
+	result := refs[:0]
+	for _, ref := range refs {
+		if ref[0] >= end {
+			result = append(result, ref)
+			end = ref[1]
+		}
+	}
+	return result
+}
+
+// resolveRef returns the virtual path of asset referenced by ref from the asset pth, and the path and the
+// query and fragment suffix of ref. External and data URLs, fragments and references out of the file
+// system are not resolved.
+func resolveRef(pth, ref string) (target, refPath, suffix string, ok bool) {
+	if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "//") {
+		return
+	}
+	if u, err := url.Parse(ref); err != nil || u.Scheme != "" {
+		return
+	}
+	refPath = ref
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		refPath, suffix = ref[:i], ref[i:]
+	}
+	if refPath == "" || strings.HasSuffix(refPath, "/") {
+		return
+	}
+	if strings.HasPrefix(refPath, "/") {
+		target = cleanPath(refPath)
+	} else if target = path.Join(path.Dir(pth), refPath); target == ".." || strings.HasPrefix(target, "../") {
+		return
+	}
+	return target, refPath, suffix, true
+}
+
+// isDocument returns if pth is a HTML document. References to documents are not rewritten: documents are
+// linked by stable paths, and may reference each other.
+func isDocument(pth string) bool {
+	switch strings.ToLower(path.Ext(pth)) {
+	case ".html", ".htm":
+		return true
+	}
+	return false
+}
+
+// rewrittenFileInfo file with references rewritten by Fingerprinter
+type rewrittenFileInfo struct {
+	assetfsapi.FileInfo
+	data   []byte
+	digest [sha256.Size]byte
+}
+
+func (r *rewrittenFileInfo) Size() int64 {
+	return int64(len(r.data))
+}
+
+func (r *rewrittenFileInfo) Reader() (io.ReadCloser, error) {
+	return mapReader{bytes.NewReader(r.data)}, nil
+}
+
+func (r *rewrittenFileInfo) Digest() [sha256.Size]byte {
+	return r.digest
+}
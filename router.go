@@ -0,0 +1,118 @@
+package assetfs
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-chi/chi"
+)
+
+// NameSpaceMount the handler of a namespace mounted by NameSpaceRouter
+type NameSpaceMount struct {
+	// Path the root path of namespace file system
+	Path    string
+	FS      *AssetFileSystem
+	Handler *StaticHandler
+	// Auth authorizes the request before serving it. If returns false, the request is not served and Auth
+	// must write the response.
+	Auth func(w http.ResponseWriter, r *http.Request) bool
+}
+
+func (m *NameSpaceMount) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.Auth != nil && !m.Auth(w, r) {
+		return
+	}
+	m.Handler.ServeHTTP(w, r)
+}
+
+// NameSpaceRouter routes each request to the mount of its namespace: namespaces of FS, and namespaces
+// of its namespaces, are mounted at its root path (see RootPath) and the other paths to the FS mount.
+// The routing table is updated when namespaces are added.
+type NameSpaceRouter struct {
+	FS *AssetFileSystem
+	// Setup configures each mount, like the cache policies, auth and error pages of its handler. It is
+	// called once per mount, also for the FS mount and for namespaces added after the first request.
+	Setup func(m *NameSpaceMount)
+
+	mu      sync.Mutex
+	mounts  map[string]*NameSpaceMount
+	buildMu sync.Mutex
+	mux     atomic.Value
+	dirty   int32
+}
+
+// NewNameSpaceRouter create new NameSpaceRouter of fs. setup is optional.
+func NewNameSpaceRouter(fs *AssetFileSystem, setup func(m *NameSpaceMount)) *NameSpaceRouter {
+	router := &NameSpaceRouter{FS: fs, Setup: setup, dirty: 1}
+	fs.OnNameSpace(func(*AssetFileSystem) {
+		atomic.StoreInt32(&router.dirty, 1)
+	})
+	return router
+}
+
+// Mount returns the mount of ns, creating it if not exists
+func (router *NameSpaceRouter) Mount(ns *AssetFileSystem) *NameSpaceMount {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	return router.mount(ns)
+}
+
+func (router *NameSpaceRouter) mount(ns *AssetFileSystem) *NameSpaceMount {
+	pth := RootPath(ns)
+	if m, ok := router.mounts[pth]; ok {
+		return m
+	}
+	if router.mounts == nil {
+		router.mounts = map[string]*NameSpaceMount{}
+	}
+	m := &NameSpaceMount{Path: pth, FS: ns, Handler: NewStaticHandler(ns)}
+	if router.Setup != nil {
+		router.Setup(m)
+	}
+	router.mounts[pth] = m
+	return m
+}
+
+// Mounts returns the mounts of FS and of all namespaces
+func (router *NameSpaceRouter) Mounts() (mounts []*NameSpaceMount) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	var walk func(fs *AssetFileSystem)
+	walk = func(fs *AssetFileSystem) {
+		mounts = append(mounts, router.mount(fs))
+		fs.load().nameSpaces.Each(func(_ string, ns *AssetFileSystem) error {
+			walk(ns)
+			return nil
+		})
+	}
+	walk(router.FS)
+	return
+}
+
+// Routes returns the chi router of mounts, built again if namespaces was added
+func (router *NameSpaceRouter) Routes() chi.Router {
+	if atomic.LoadInt32(&router.dirty) == 0 {
+		if mux := router.mux.Load(); mux != nil {
+			return mux.(chi.Router)
+		}
+	}
+	router.buildMu.Lock()
+	defer router.buildMu.Unlock()
+	if atomic.CompareAndSwapInt32(&router.dirty, 1, 0) || router.mux.Load() == nil {
+		mux := chi.NewRouter()
+		for _, m := range router.Mounts() {
+			pattern := m.Path
+			if pattern == "" {
+				pattern = "/"
+			}
+			mux.Mount(pattern, m)
+		}
+		router.mux.Store(chi.Router(mux))
+	}
+	return router.mux.Load().(chi.Router)
+}
+
+func (router *NameSpaceRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router.Routes().ServeHTTP(w, r)
+}
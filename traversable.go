@@ -1,6 +1,8 @@
 package assetfs
 
 import (
+	"context"
+
 	"github.com/moisespsena-go/assetfs/assetfsapi"
 )
 
@@ -11,6 +13,12 @@ type Traversable struct {
 	ReadDirFunc  func(dir string, cb assetfsapi.CbWalkInfoFunc, skipDir bool) error
 	GlobFunc     assetfsapi.GlobFunc
 	GlobInfoFunc assetfsapi.GlobInfoFunc
+
+	// WalkInfoFuncC, ReadDirFuncC and GlobInfoFuncC are the optional context aware functions. If not set,
+	// the function without context is used.
+	WalkInfoFuncC assetfsapi.WalkInfoFuncC
+	ReadDirFuncC  assetfsapi.ReadDirFuncC
+	GlobInfoFuncC assetfsapi.GlobInfoFuncC
 }
 
 func (t *Traversable) Walk(dir string, cb assetfsapi.CbWalkFunc, mode ...assetfsapi.WalkMode) error {
@@ -33,19 +41,56 @@ func (t *Traversable) ReadDir(dir string, cb assetfsapi.CbWalkInfoFunc, skipDir
 	return t.ReadDirFunc(dir, cb, skipDir)
 }
 
-func (f *Traversable) Glob(pattern assetfsapi.GlobPattern, cb func(pth string, isDir bool) error) error {
-	if pth := f.FS.GetPath(); pth != "" {
-		l := len(pth)
-		oldFormatter := pattern.GetPathFormatter()
-		pattern = pattern.PathFormatter(func(pth *string) {
-			*pth = (*pth)[l+1:]
-			oldFormatter(pth)
-		})
+func (t *Traversable) WalkC(ctx context.Context, dir string, cb assetfsapi.CbWalkFunc, mode ...assetfsapi.WalkMode) error {
+	return t.WalkInfoC(ctx, dir, func(info assetfsapi.FileInfo) error {
+		return cb(info.Path(), info.IsDir())
+	}, mode...)
+}
+
+func (t *Traversable) WalkInfoC(ctx context.Context, dir string, cb assetfsapi.CbWalkInfoFunc, mode ...assetfsapi.WalkMode) error {
+	m := assetfsapi.WalkAll
+	if len(mode) > 0 {
+		m = mode[0]
+	}
+	cb = cancelableCb(ctx, cb)
+	if t.WalkInfoFuncC != nil {
+		return t.WalkInfoFuncC(ctx, dir, cb, m)
 	}
-	return f.GlobFunc(pattern, cb)
+	return t.WalkInfoFunc(dir, cb, m)
+}
+
+func (t *Traversable) ReadDirC(ctx context.Context, dir string, cb assetfsapi.CbWalkInfoFunc, skipDir bool) (err error) {
+	cb = cancelableCb(ctx, cb)
+	if t.ReadDirFuncC != nil {
+		return t.ReadDirFuncC(ctx, dir, cb, skipDir)
+	}
+	return t.ReadDirFunc(dir, cb, skipDir)
+}
+
+func (f *Traversable) Glob(pattern assetfsapi.GlobPattern, cb func(pth string, isDir bool) error) error {
+	return f.GlobFunc(f.pattern(pattern), cb)
 }
 
 func (f *Traversable) GlobInfo(pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
+	return f.GlobInfoFunc(f.pattern(pattern), cb)
+}
+
+func (f *Traversable) GlobC(ctx context.Context, pattern assetfsapi.GlobPattern, cb func(pth string, isDir bool) error) error {
+	return f.GlobInfoC(ctx, pattern, func(info assetfsapi.FileInfo) error {
+		return cb(info.Path(), info.IsDir())
+	})
+}
+
+func (f *Traversable) GlobInfoC(ctx context.Context, pattern assetfsapi.GlobPattern, cb func(info assetfsapi.FileInfo) error) error {
+	cb = cancelableCb(ctx, cb)
+	if f.GlobInfoFuncC != nil {
+		return f.GlobInfoFuncC(ctx, f.pattern(pattern), cb)
+	}
+	return f.GlobInfoFunc(f.pattern(pattern), cb)
+}
+
+// pattern returns pattern with path formatter which removes the path of FS
+func (f *Traversable) pattern(pattern assetfsapi.GlobPattern) assetfsapi.GlobPattern {
 	if pth := f.FS.GetPath(); pth != "" {
 		l := len(pth)
 		oldFormatter := pattern.GetPathFormatter()
@@ -54,7 +99,7 @@ func (f *Traversable) GlobInfo(pattern assetfsapi.GlobPattern, cb func(info asse
 			oldFormatter(pth)
 		})
 	}
-	return f.GlobInfoFunc(pattern, cb)
+	return pattern
 }
 
 func (f *Traversable) NewGlob(pattern assetfsapi.GlobPattern) assetfsapi.Glob {
@@ -64,3 +109,16 @@ func (f *Traversable) NewGlob(pattern assetfsapi.GlobPattern) assetfsapi.Glob {
 func (f *Traversable) NewGlobString(pattern string) assetfsapi.Glob {
 	return NewGlob(f.FS, NewGlobPattern(pattern))
 }
+
+// cancelableCb returns cb which returns ctx.Err() instead of calling cb when ctx is done
+func cancelableCb(ctx context.Context, cb assetfsapi.CbWalkInfoFunc) assetfsapi.CbWalkInfoFunc {
+	if ctx == nil || ctx.Done() == nil {
+		return cb
+	}
+	return func(info assetfsapi.FileInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return cb(info)
+	}
+}
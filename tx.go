@@ -0,0 +1,465 @@
+package assetfs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-errors/errors"
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+	oscommon "github.com/moisespsena-go/os-common"
+)
+
+var TX_DONE_ERROR = errors.New("Transaction is done.")
+
+const (
+	// txData directory of staged files of transaction directory
+	txData = "data"
+	// txJournal file of committed transaction directory with the removes to apply
+	txJournal = "commit"
+)
+
+// Tx transaction of writes into the writable layer. Writes are staged into a temporary directory, created
+// next to the layer directory, and are visible only after Commit, all together: readers see either the old
+// set of files or the new one.
+type Tx struct {
+	// Sync if the staged files, the journal and the changed directories are synced to disk
+	Sync bool
+
+	fs    *AssetFileSystem
+	owner *AssetFileSystem
+	layer *DirLayer
+	dir   string
+	stage *txStageLayer
+	// removes the layer paths to remove and if it is provided by other layers, so it requires a whiteout
+	removes map[string]bool
+	mu      sync.Mutex
+	done    bool
+}
+
+// writableOwner returns fs or the nearest parent which has writable layer
+func (fs *AssetFileSystem) writableOwner() (owner *AssetFileSystem, err error) {
+	owner = fs
+	for owner.load().writable == nil {
+		if owner.parent == nil {
+			return nil, READ_ONLY_ERROR
+		}
+		owner = owner.parent.(*AssetFileSystem)
+	}
+	return
+}
+
+// Begin starts a transaction of writes into the writable layer of fs or of the nearest parent
+func (fs *AssetFileSystem) Begin() (tx *Tx, err error) {
+	var owner *AssetFileSystem
+	if owner, err = fs.writableOwner(); err != nil {
+		return
+	}
+	layer := owner.load().writable
+	dir, err := ioutil.TempDir(filepath.Dir(layer.Dir), txPrefix(layer))
+	if err != nil {
+		return
+	}
+	if err = os.Mkdir(filepath.Join(dir, txData), DefaultDirPerm); err != nil {
+		os.RemoveAll(dir)
+		return
+	}
+	return &Tx{
+		fs:      fs,
+		owner:   owner,
+		layer:   layer,
+		dir:     dir,
+		stage:   &txStageLayer{NewDirLayer(filepath.Join(dir, txData)), layer.Dir},
+		removes: map[string]bool{},
+	}, nil
+}
+
+// txPrefix returns the name prefix of the transaction directories of layer
+func txPrefix(layer *DirLayer) string {
+	return "." + filepath.Base(layer.Dir) + ".tx-"
+}
+
+// rel returns the path of t into the layer of tx
+func (tx *Tx) rel(t *writeTarget) (rel string, err error) {
+	if t.layer != tx.layer {
+		return "", READ_ONLY_ERROR
+	}
+	if rel, err = filepath.Rel(tx.layer.Dir, t.realPath); err != nil {
+		return
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// WriteFile stages data to file pth. See AssetFileSystem.WriteFileC.
+func (tx *Tx) WriteFile(pth string, data []byte, perm os.FileMode) (err error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return TX_DONE_ERROR
+	}
+	ctx := context.Background()
+	t, err := tx.fs.writeTarget(ctx, pth)
+	if err != nil {
+		return
+	}
+	var rel string
+	if rel, err = tx.rel(t); err != nil {
+		return
+	}
+	if info, err := tx.fs.AssetInfoC(ctx, t.pth); err == nil {
+		if info.Type().IsNameSpace() {
+			return IS_NS_ERROR
+		}
+		if info.IsDir() {
+			return IS_DIR_ERROR
+		}
+		perm = info.Mode().Perm()
+	} else if !isLayerSkipError(err) {
+		return err
+	}
+	if err = tx.stageDir(path.Dir(rel)); err != nil {
+		return
+	}
+	staged := tx.stage.realPath(rel)
+	if err = writeFile(staged, data, perm, tx.Sync); err != nil {
+		return
+	}
+	if err = removeIfExists(realWhiteoutOf(staged)); err != nil {
+		return
+	}
+	delete(tx.removes, rel)
+	return
+}
+
+// Remove stages the remove of pth. See AssetFileSystem.RemoveC.
+func (tx *Tx) Remove(pth string) (err error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return TX_DONE_ERROR
+	}
+	ctx := context.Background()
+	t, err := tx.fs.writeTarget(ctx, pth)
+	if err != nil {
+		return
+	}
+	var rel string
+	if rel, err = tx.rel(t); err != nil {
+		return
+	}
+	staged := tx.stage.realPath(rel)
+	_, err = os.Stat(staged)
+	wasStaged := err == nil
+	if err = os.RemoveAll(staged); err != nil {
+		return
+	}
+	_, _, lower, err := tx.fs.removable(ctx, t.pth)
+	if err != nil {
+		if wasStaged && oscommon.IsNotFound(err) {
+			return nil
+		}
+		return
+	}
+	if err = tx.stageDir(path.Dir(rel)); err != nil {
+		return
+	}
+	if err = writeFile(realWhiteoutOf(staged), nil, 0644, tx.Sync); err != nil {
+		return
+	}
+	tx.removes[rel] = lower
+	return
+}
+
+// stageDir creates the directory dir and all parents into the stage directory, with the permissions of the
+// directories provided by the layers
+func (tx *Tx) stageDir(dir string) (err error) {
+	if dir = cleanPath(dir); dir == "." {
+		return
+	}
+	var pth string
+	for _, name := range strings.Split(dir, "/") {
+		pth = joinPath(pth, name)
+		staged := tx.stage.realPath(pth)
+		if info, err := os.Stat(staged); err == nil {
+			if !info.IsDir() {
+				return IS_NOT_DIR_ERROR
+			}
+			continue
+		}
+		perm := DefaultDirPerm
+		if info, err := tx.owner.AssetInfoC(context.Background(), pth); err == nil && info.IsDir() && info.Mode().Perm() != 0 {
+			perm = info.Mode().Perm()
+		}
+		if err = os.Mkdir(staged, perm); err != nil {
+			return
+		}
+		// the directory replaces a removed one: contents of other layers keeps hidden
+		if _, err := os.Stat(realWhiteoutOf(staged)); err == nil {
+			if err = writeFile(filepath.Join(staged, WhiteoutOpaque), nil, 0644, tx.Sync); err != nil {
+				return err
+			}
+			if err = os.Remove(realWhiteoutOf(staged)); err != nil {
+				return err
+			}
+		}
+	}
+	return
+}
+
+// Commit applies the staged changes. The journal is written before apply, so an interrupted commit is
+// finished by the next RecoverTx.
+func (tx *Tx) Commit() (err error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return TX_DONE_ERROR
+	}
+	tx.done = true
+
+	tx.owner.txMu.Lock()
+	defer tx.owner.txMu.Unlock()
+
+	var data []byte
+	if data, err = json.Marshal(tx.removes); err != nil {
+		return
+	}
+	journal := filepath.Join(tx.dir, txJournal)
+	if err = writeFile(journal+".tmp", data, 0644, tx.Sync); err != nil {
+		return
+	}
+	if err = os.Rename(journal+".tmp", journal); err != nil {
+		return
+	}
+
+	// the stage layer, immediately above the writable layer, shows the new files while they are moved into
+	// the writable layer. If the move fails, it keeps registered, so the new files keeps visible.
+	tx.owner.update(func(s *fileSystemState) bool {
+		i := 0
+		for j, l := range s.layers {
+			if l == assetfsapi.Layer(tx.layer) {
+				i = j
+				break
+			}
+		}
+		layers := make([]assetfsapi.Layer, 0, len(s.layers)+1)
+		layers = append(layers, s.layers[:i]...)
+		layers = append(layers, tx.stage)
+		s.layers = append(layers, s.layers[i:]...)
+		return true
+	})
+	defer tx.layer.whiteouts.reset()
+	if err = txApply(tx.stage.Dir, tx.layer.Dir, tx.removes, tx.Sync); err != nil {
+		return
+	}
+	tx.owner.update(func(s *fileSystemState) bool {
+		layers := make([]assetfsapi.Layer, 0, len(s.layers))
+		for _, l := range s.layers {
+			if l != assetfsapi.Layer(tx.stage) {
+				layers = append(layers, l)
+			}
+		}
+		s.layers = layers
+		return true
+	})
+	return os.RemoveAll(tx.dir)
+}
+
+// Rollback discards the staged changes
+func (tx *Tx) Rollback() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return TX_DONE_ERROR
+	}
+	tx.done = true
+	return os.RemoveAll(tx.dir)
+}
+
+// txStageLayer the layer of staged files of a transaction, registered while the commit moves its files into
+// the writable layer. Readers of its file infos fall back to the moved file.
+type txStageLayer struct {
+	*DirLayer
+	dst string
+}
+
+func (l *txStageLayer) AssetInfoC(ctx context.Context, pth string) (info assetfsapi.FileInfo, err error) {
+	if info, err = l.DirLayer.AssetInfoC(ctx, pth); err != nil {
+		return
+	}
+	return l.wrap(info, pth), nil
+}
+
+func (l *txStageLayer) ReadDir(dir string, cb assetfsapi.CbWalkInfoFunc, skipDir bool) error {
+	return l.DirLayer.ReadDir(dir, func(info assetfsapi.FileInfo) error {
+		return cb(l.wrap(info, info.Path()))
+	}, skipDir)
+}
+
+func (l *txStageLayer) wrap(info assetfsapi.FileInfo, pth string) assetfsapi.FileInfo {
+	if rf, ok := info.(*RealFileInfo); ok {
+		return &txFileInfo{rf, filepath.Join(l.dst, filepath.FromSlash(cleanPath(pth)))}
+	}
+	return info
+}
+
+// txFileInfo file info of the stage layer
+type txFileInfo struct {
+	*RealFileInfo
+	moved string
+}
+
+// Reader opens the staged file, or the moved file into the writable layer if it is already moved
+func (f *txFileInfo) Reader() (io.ReadCloser, error) {
+	r, err := f.RealFileInfo.Reader()
+	if os.IsNotExist(err) {
+		return os.Open(f.moved)
+	}
+	return r, err
+}
+
+// txApply removes the paths of removes from directory dst, creating whiteouts for those provided by other
+// layers, then moves the staged files of directory stage into dst
+func txApply(stage, dst string, removes map[string]bool, sync bool) (err error) {
+	changed := map[string]bool{}
+	rels := make([]string, 0, len(removes))
+	for rel := range removes {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	for _, rel := range rels {
+		realPath := filepath.Join(dst, filepath.FromSlash(rel))
+		if err = os.RemoveAll(realPath); err != nil {
+			return
+		}
+		changed[filepath.Dir(realPath)] = true
+		if removes[rel] {
+			if err = os.MkdirAll(filepath.Dir(realPath), DefaultDirPerm); err != nil {
+				return
+			}
+			if err = writeFile(realWhiteoutOf(realPath), nil, 0644, sync); err != nil {
+				return
+			}
+		}
+	}
+
+	err = filepath.Walk(stage, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(stage, pth)
+		if err != nil || rel == "." || IsWhiteout(info.Name()) {
+			return err
+		}
+		realPath := filepath.Join(dst, rel)
+		if info.IsDir() {
+			if rinfo, err := os.Stat(realPath); err == nil {
+				if !rinfo.IsDir() {
+					return IS_NOT_DIR_ERROR
+				}
+				return nil
+			}
+			if err = os.Mkdir(realPath, info.Mode().Perm()); err != nil {
+				return err
+			}
+			changed[filepath.Dir(realPath)] = true
+			if _, err = os.Stat(realWhiteoutOf(realPath)); err == nil {
+				if err = writeFile(filepath.Join(realPath, WhiteoutOpaque), nil, 0644, sync); err != nil {
+					return err
+				}
+			}
+			return removeIfExists(realWhiteoutOf(realPath))
+		}
+		if err = os.Rename(pth, realPath); err != nil {
+			return err
+		}
+		changed[filepath.Dir(realPath)] = true
+		return removeIfExists(realWhiteoutOf(realPath))
+	})
+	if err != nil || !sync {
+		return
+	}
+	for dir := range changed {
+		if err = syncDir(dir); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// RecoverTx finishes the interrupted commits of transactions into the writable layer of fs or of the
+// nearest parent, and removes the transactions which were not committed. Call it before beginning
+// transactions, like on start up, because the transactions which are not committed yet are removed.
+func (fs *AssetFileSystem) RecoverTx() (err error) {
+	var owner *AssetFileSystem
+	if owner, err = fs.writableOwner(); err != nil {
+		return
+	}
+	owner.txMu.Lock()
+	defer owner.txMu.Unlock()
+	return recoverTx(owner.load().writable)
+}
+
+// recoverTx finishes the committed transactions of layer which were interrupted and removes the others
+func recoverTx(layer *DirLayer) (err error) {
+	defer layer.whiteouts.reset()
+	var dirs []string
+	if dirs, err = filepath.Glob(filepath.Join(filepath.Dir(layer.Dir), txPrefix(layer)+"*")); err != nil {
+		return
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		if data, err := ioutil.ReadFile(filepath.Join(dir, txJournal)); err == nil {
+			var removes map[string]bool
+			if err = json.Unmarshal(data, &removes); err != nil {
+				return err
+			}
+			if err = txApply(filepath.Join(dir, txData), layer.Dir, removes, true); err != nil {
+				return err
+			}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		if err = os.RemoveAll(dir); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// writeFile is like ioutil.WriteFile, but syncs the file to disk if sync
+func writeFile(pth string, data []byte, perm os.FileMode, sync bool) (err error) {
+	var f *os.File
+	if f, err = os.OpenFile(pth, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm); err != nil {
+		return
+	}
+	if _, err = f.Write(data); err == nil && sync {
+		err = f.Sync()
+	}
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return
+}
+
+func removeIfExists(pth string) error {
+	if err := os.Remove(pth); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
@@ -0,0 +1,145 @@
+package assetfs
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+// DefaultWatchInterval default polling interval of Watch
+var DefaultWatchInterval = time.Second
+
+type WatchOp int
+
+const (
+	WatchCreate WatchOp = iota + 1
+	WatchModify
+	WatchDelete
+	WatchError
+)
+
+func (op WatchOp) String() string {
+	switch op {
+	case WatchCreate:
+		return "create"
+	case WatchModify:
+		return "modify"
+	case WatchDelete:
+		return "delete"
+	case WatchError:
+		return "error"
+	}
+	return "unknown"
+}
+
+// WatchEvent change of a virtual path. Info is the new file info, or the last known info on delete.
+// Events of WatchError op have the snapshot error in Err.
+type WatchEvent struct {
+	Op   WatchOp
+	Path string
+	Info assetfsapi.FileInfo
+	Err  error
+}
+
+// Watch polls dir, which may also be a file, and calls cb with the changes of its virtual paths until ctx
+// is done or cb returns an error. Changes are resolved by overlay priority: a file of a layer that is
+// shadowed by a file of a higher priority layer does not produce events, and a file that becomes shadowed or
+// unshadowed produces a WatchModify event. Directories produce only create and delete events.
+// If a poll fails, cb is called with a WatchError event: the watch stops if cb returns an error, otherwise
+// it retries on next tick. The optional interval defaults to DefaultWatchInterval.
+func (fs *AssetFileSystem) Watch(ctx context.Context, dir string, cb func(event WatchEvent) error, interval ...time.Duration) (err error) {
+	d := DefaultWatchInterval
+	if len(interval) > 0 && interval[0] > 0 {
+		d = interval[0]
+	}
+
+	dir = cleanPath(dir)
+	last, err := fs.watchSnapshot(ctx, dir)
+	if err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		cur, err := fs.watchSnapshot(ctx, dir)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			// files can be removed while walking: cb decides to retry on next tick
+			if err = cb(WatchEvent{Op: WatchError, Path: dir, Err: err}); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, e := range watchDiff(last, cur) {
+			if err = cb(e); err != nil {
+				return err
+			}
+		}
+		last = cur
+	}
+}
+
+func (fs *AssetFileSystem) watchSnapshot(ctx context.Context, dir string) (snap map[string]assetfsapi.FileInfo, err error) {
+	snap = map[string]assetfsapi.FileInfo{}
+	if dir != "." {
+		var info assetfsapi.FileInfo
+		if info, err = fs.AssetInfoC(ctx, dir); err != nil {
+			if isLayerSkipError(err) {
+				return snap, nil
+			}
+			return
+		}
+		if !info.IsDir() {
+			snap[dir] = info
+			return
+		}
+	}
+	err = fs.WalkInfoC(ctx, dir, func(info assetfsapi.FileInfo) error {
+		snap[info.Path()] = info
+		return nil
+	}, assetfsapi.WalkAll)
+	return
+}
+
+// watchDiff returns the events of changes from old to cur: deletes in reverse path order, so children
+// comes before its parent, then creates and modifies in path order.
+func watchDiff(old, cur map[string]assetfsapi.FileInfo) (events []WatchEvent) {
+	var deleted, names []string
+	for pth := range old {
+		if _, ok := cur[pth]; !ok {
+			deleted = append(deleted, pth)
+		}
+	}
+	for pth := range cur {
+		names = append(names, pth)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(deleted)))
+	sort.Strings(names)
+
+	for _, pth := range deleted {
+		events = append(events, WatchEvent{Op: WatchDelete, Path: pth, Info: old[pth]})
+	}
+	for _, pth := range names {
+		info := cur[pth]
+		if o, ok := old[pth]; !ok {
+			events = append(events, WatchEvent{Op: WatchCreate, Path: pth, Info: info})
+		} else if o.IsDir() != info.IsDir() {
+			events = append(events, WatchEvent{Op: WatchDelete, Path: pth, Info: o}, WatchEvent{Op: WatchCreate, Path: pth, Info: info})
+		} else if !info.IsDir() && (o.RealPath() != info.RealPath() || o.Size() != info.Size() ||
+			!o.ModTime().Equal(info.ModTime()) || o.Mode() != info.Mode()) {
+			events = append(events, WatchEvent{Op: WatchModify, Path: pth, Info: info})
+		}
+	}
+	return
+}
@@ -0,0 +1,208 @@
+package assetfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+)
+
+const (
+	// WhiteoutPrefix prefix of whiteout file names. The file `.wh.<name>` of a layer or local source hides
+	// `<name>` of the same directory from the lower priority layers.
+	WhiteoutPrefix = ".wh."
+	// WhiteoutOpaque the file of a directory of a layer or local source which hides the contents of the
+	// same directory from the lower priority layers.
+	WhiteoutOpaque = WhiteoutPrefix + WhiteoutPrefix + ".opq"
+)
+
+// IsWhiteout returns if name is the name of a whiteout file
+func IsWhiteout(name string) bool {
+	return strings.HasPrefix(name, WhiteoutPrefix)
+}
+
+// realWhiteoutOf returns the whiteout path of real path pth
+func realWhiteoutOf(pth string) string {
+	return filepath.Join(filepath.Dir(pth), WhiteoutPrefix+filepath.Base(pth))
+}
+
+// whitedOut returns if pth is hidden from the lower priority layers by the whiteouts of a layer: the
+// whiteout of pth or of any parent, or the opaque file of any parent. exists reports if a whiteout path
+// exists into the layer.
+func whitedOut(pth string, exists func(pth string) bool) bool {
+	if pth = cleanPath(pth); pth == "." {
+		return false
+	}
+	var dir = "."
+	for _, name := range strings.Split(pth, "/") {
+		if exists(joinPath(dir, WhiteoutPrefix+name)) || exists(joinPath(dir, WhiteoutOpaque)) {
+			return true
+		}
+		dir = joinPath(dir, name)
+	}
+	return false
+}
+
+// whiteoutsGeneration is incremented by ResetWhiteouts, so all whiteout caches are reloaded
+var whiteoutsGeneration uint64
+
+// ResetWhiteouts clears the caches of whiteouts of all directory layers and local sources. The caches are
+// reloaded when the modification time of a directory changes and the writes of AssetFileSystem clears the
+// cache of its target, so call it only after changing whiteout files by other means into file systems which
+// modification times are too coarse to detect the changes.
+func ResetWhiteouts() {
+	atomic.AddUint64(&whiteoutsGeneration, 1)
+}
+
+// whiteoutDir whiteouts of a directory of a layer or local source
+type whiteoutDir struct {
+	// names hidden by whiteouts
+	names map[string]bool
+	// opaque if has the opaque file
+	opaque bool
+	// dirs subdirectories, which may have whiteouts
+	dirs map[string]bool
+	// modTime modification time of the directory when loaded
+	modTime time.Time
+}
+
+// whiteoutCache cache of whiteouts by directory of a layer or local source. Each directory is reloaded when
+// its modification time changes.
+type whiteoutCache struct {
+	mu sync.RWMutex
+	// generation is incremented by reset
+	generation uint64
+	// global value of whiteoutsGeneration when dirs was created
+	global uint64
+	dirs   map[string]*whiteoutDir
+}
+
+// reset clears the cache
+func (c *whiteoutCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.dirs = nil
+}
+
+// dir returns the whiteouts of directory pth of real directory root, loaded by read if not cached or if
+// the directory was modified
+func (c *whiteoutCache) dir(root, pth string, read func(dir string, cb func(name string, isDir bool)) error) (d *whiteoutDir, err error) {
+	var (
+		global  = atomic.LoadUint64(&whiteoutsGeneration)
+		modTime time.Time
+	)
+	if info, err := os.Stat(filepath.Join(root, filepath.FromSlash(pth))); err == nil {
+		modTime = info.ModTime()
+	}
+	c.mu.RLock()
+	generation := c.generation
+	if c.global == global {
+		d = c.dirs[pth]
+	}
+	c.mu.RUnlock()
+	if d != nil && d.modTime.Equal(modTime) {
+		return
+	}
+
+	d = &whiteoutDir{names: map[string]bool{}, dirs: map[string]bool{}, modTime: modTime}
+	if err = read(pth, func(name string, isDir bool) {
+		switch {
+		case name == WhiteoutOpaque:
+			d.opaque = true
+		case IsWhiteout(name):
+			d.names[strings.TrimPrefix(name, WhiteoutPrefix)] = true
+		case isDir:
+			d.dirs[name] = true
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		// reset while reading: d may be stale
+		return
+	}
+	if c.global != global || c.dirs == nil {
+		c.global, c.dirs = global, map[string]*whiteoutDir{}
+	}
+	c.dirs[pth] = d
+	return
+}
+
+// whitedOut returns if pth is hidden by the cached whiteouts of real directory root. Directories which does
+// not exists into the layer have no whiteouts, so the parents are read only while they exist.
+func (c *whiteoutCache) whitedOut(root, pth string, read func(dir string, cb func(name string, isDir bool)) error) bool {
+	if pth = cleanPath(pth); pth == "." {
+		return false
+	}
+	var dir = "."
+	for _, name := range strings.Split(pth, "/") {
+		d, err := c.dir(root, dir, read)
+		if err != nil {
+			return false
+		}
+		if d.opaque || d.names[name] {
+			return true
+		}
+		if !d.dirs[name] {
+			return false
+		}
+		dir = joinPath(dir, name)
+	}
+	return false
+}
+
+// layerWhitedOut returns if pth is hidden by the whiteouts of layer
+func layerWhitedOut(ctx context.Context, layer assetfsapi.Layer, pth string) bool {
+	if l, ok := layer.(*DirLayer); ok {
+		return l.whiteouts.whitedOut(l.Dir, pth, func(dir string, cb func(name string, isDir bool)) error {
+			err := l.ReadDir(dir, func(info assetfsapi.FileInfo) error {
+				cb(info.Name(), info.IsDir())
+				return nil
+			}, false)
+			if isLayerSkipError(err) {
+				return nil
+			}
+			return err
+		})
+	}
+	return whitedOut(pth, func(pth string) bool {
+		_, err := layer.AssetInfoC(ctx, pth)
+		return err == nil
+	})
+}
+
+// sourceWhiteouts whiteout caches of local sources by directory
+var sourceWhiteouts sync.Map
+
+// sourceWhitedOut returns if pth, relative to the root file system, is hidden by the whiteouts of src
+func sourceWhitedOut(src assetfsapi.LocalSource, pth string) bool {
+	if src.Dir() != "" {
+		c, _ := sourceWhiteouts.LoadOrStore(src.Dir(), &whiteoutCache{})
+		return c.(*whiteoutCache).whitedOut(src.Dir(), pth, func(dir string, cb func(name string, isDir bool)) error {
+			return readSourceDir(src, dir, func(name, _ string, info os.FileInfo) error {
+				cb(name, info.IsDir())
+				return nil
+			})
+		})
+	}
+	return whitedOut(pth, func(pth string) bool {
+		_, err := src.Get(pth)
+		return err == nil
+	})
+}
+
+// resetSourceWhiteouts clears the whiteouts cache of src
+func resetSourceWhiteouts(src assetfsapi.LocalSource) {
+	if c, ok := sourceWhiteouts.Load(src.Dir()); ok {
+		c.(*whiteoutCache).reset()
+	}
+}
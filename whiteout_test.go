@@ -0,0 +1,51 @@
+package assetfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWhiteoutExternalChanges checks that whiteouts created by other means are seen without ResetWhiteouts
+func TestWhiteoutExternalChanges(t *testing.T) {
+	upper, lower := t.TempDir(), t.TempDir()
+	if err := os.MkdirAll(filepath.Join(lower, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "d/b.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(lower, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(upper, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewAssetFileSystem()
+	fs.RegisterPath(upper)
+	fs.RegisterPath(lower)
+	for _, pth := range []string{"a.txt", "d/b.txt"} {
+		if _, err := fs.AssetInfo(pth); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the modification time is set, because it may not change into file systems with coarse times
+	modTime := time.Now().Add(time.Hour)
+	for _, pth := range []string{".wh.a.txt", "d/.wh.b.txt"} {
+		realPath := filepath.Join(upper, pth)
+		if err := ioutil.WriteFile(realPath, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(filepath.Dir(realPath), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, pth := range []string{"a.txt", "d/b.txt"} {
+		if _, err := fs.AssetInfo(pth); err == nil {
+			t.Errorf("%s: not whited out", pth)
+		}
+	}
+}
@@ -0,0 +1,484 @@
+package assetfs
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/moisespsena-go/assetfs/assetfsapi"
+	"github.com/moisespsena-go/assetfs/local"
+	oscommon "github.com/moisespsena-go/os-common"
+)
+
+// DefaultDirPerm permissions of directories created into the writable layer which are not provided by other
+// layers
+var DefaultDirPerm os.FileMode = 0755
+
+// SetWritablePath sets the directory pth as the writable layer of fs, creating it if not exists, and
+// recovers its transactions. See SetWritableLayer and RecoverTx.
+func (fs *AssetFileSystem) SetWritablePath(pth string) error {
+	pth = filepath.Clean(pth)
+	if err := os.MkdirAll(pth, DefaultDirPerm); err != nil {
+		return err
+	}
+	fs.SetWritableLayer(NewDirLayer(pth))
+	return fs.RecoverTx()
+}
+
+// SetWritableLayer sets layer as the writable layer of fs. It receives the writes of fs and of the
+// namespaces without writable layer. If layer is not registered, it is prepended, so it has the higher
+// priority.
+func (fs *AssetFileSystem) SetWritableLayer(layer *DirLayer) {
+	fs.registerLayer(layer, true)
+	fs.update(func(s *fileSystemState) bool {
+		s.writable = layer
+		for _, l := range s.layers {
+			if dl, ok := l.(*DirLayer); ok && dl.Dir == layer.Dir {
+				s.writable = dl
+				break
+			}
+		}
+		return true
+	})
+}
+
+// WritableLayer returns the writable layer of fs or of the nearest parent
+func (fs *AssetFileSystem) WritableLayer() *DirLayer {
+	for {
+		if w := fs.load().writable; w != nil || fs.parent == nil {
+			return w
+		}
+		fs = fs.parent.(*AssetFileSystem)
+	}
+}
+
+// SetWritableSource sets the name of local source which receives the writes, instead of the writable
+// layer, when the context uses it by name (see local.SetNames).
+func (fs *AssetFileSystem) SetWritableSource(name string) {
+	fs.update(func(s *fileSystemState) bool {
+		s.writableSource = name
+		return true
+	})
+}
+
+// writableSource returns the writable local source of ctx
+func (fs *AssetFileSystem) writableSource(ctx context.Context) assetfsapi.LocalSource {
+	var name string
+	for cur := fs; name == "" && cur != nil; {
+		name = cur.load().writableSource
+		if cur.parent == nil {
+			break
+		}
+		cur = cur.parent.(*AssetFileSystem)
+	}
+	register := fs.localSourcesRegister()
+	if name == "" || register == nil {
+		return nil
+	}
+	for _, n := range local.GetNames(ctx) {
+		if n == name {
+			if src := register.Get(name); src != nil && src.Dir() != "" {
+				return src
+			}
+		}
+	}
+	return nil
+}
+
+// writeTarget the real path of a virtual path into the writable local source or layer
+type writeTarget struct {
+	src      assetfsapi.LocalSource
+	layer    *DirLayer
+	pth      string
+	realPath string
+}
+
+// is returns if c is the candidate of t
+func (t *writeTarget) is(c *Candidate) bool {
+	if t.src != nil {
+		return c.LocalSource == t.src
+	}
+	return c.Layer != nil && c.Layer == assetfsapi.Layer(t.layer)
+}
+
+// outranks returns if candidate c has higher priority than t, so c can't be hidden by a whiteout of t and
+// hides the writes to t. Local sources outranks the layers and the lower local sources of ctx.
+func (fs *AssetFileSystem) outranks(ctx context.Context, t *writeTarget, c *Candidate) bool {
+	if c.LocalSource == nil || t.is(c) {
+		return false
+	}
+	if t.src == nil {
+		return true
+	}
+	for _, src := range local.AllSources(fs.localSourcesRegister(), ctx) {
+		if src == t.src {
+			return false
+		}
+		if src == c.LocalSource {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOutranked returns READ_ONLY_ERROR if a local source with higher priority than t provides the path
+// of t, so the writes to t are hidden.
+func (fs *AssetFileSystem) checkOutranked(ctx context.Context, t *writeTarget) error {
+	candidates, err := fs.Resolve(ctx, t.pth)
+	if err != nil {
+		return err
+	}
+	for _, c := range candidates {
+		if fs.outranks(ctx, t, c) {
+			return READ_ONLY_ERROR
+		}
+	}
+	return nil
+}
+
+// writeTarget returns the target of pth: the writable local source of ctx, or the nearest writable layer
+// of the namespace of pth.
+func (fs *AssetFileSystem) writeTarget(ctx context.Context, pth string) (t *writeTarget, err error) {
+	pth = cleanPath(pth)
+	if src := fs.writableSource(ctx); src != nil {
+		return &writeTarget{src: src, pth: pth, realPath: filepath.Join(src.Dir(), local.FilePath(fs.rootPath(pth)))}, nil
+	}
+	err = fs.eachLayerOf(pth, layerLookUp{nameSpaces: true, parents: true}, func(owner *AssetFileSystem, layer assetfsapi.Layer, lpth string) error {
+		if w := owner.load().writable; w != nil && layer == assetfsapi.Layer(w) {
+			t = &writeTarget{layer: w, pth: pth, realPath: w.realPath(lpth)}
+			return io.EOF
+		}
+		return nil
+	})
+	if err == io.EOF {
+		return t, nil
+	}
+	if err == nil {
+		err = READ_ONLY_ERROR
+	}
+	return nil, err
+}
+
+// removable returns the write target of pth and the info of pth. lower reports if pth is provided by other
+// layers, so it must be hidden by a whiteout. If pth is provided by a local source with higher priority than
+// the write target, it can't be hidden and returns READ_ONLY_ERROR.
+func (fs *AssetFileSystem) removable(ctx context.Context, pth string) (t *writeTarget, info assetfsapi.FileInfo, lower bool, err error) {
+	if pth = cleanPath(pth); pth == "." {
+		return nil, nil, false, IS_NS_ERROR
+	}
+	if t, err = fs.writeTarget(ctx, pth); err != nil {
+		return
+	}
+	candidates, err := fs.Resolve(ctx, pth)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if len(candidates) == 0 {
+		return nil, nil, false, oscommon.ErrNotFound(pth)
+	}
+	for _, c := range candidates {
+		if c.Info.Type().IsNameSpace() {
+			return nil, nil, false, IS_NS_ERROR
+		}
+		if fs.outranks(ctx, t, c) {
+			return nil, nil, false, READ_ONLY_ERROR
+		}
+		if !t.is(c) {
+			lower = true
+		}
+	}
+	return t, candidates[0].Info, lower, nil
+}
+
+// whiteout creates the whiteout file of t
+// resetWhiteouts clears the whiteouts cache of t
+func (t *writeTarget) resetWhiteouts() {
+	if t.layer != nil {
+		t.layer.whiteouts.reset()
+	} else if t.src != nil {
+		resetSourceWhiteouts(t.src)
+	}
+}
+
+func (t *writeTarget) whiteout() error {
+	return ioutil.WriteFile(t.whiteoutPath(), nil, 0644)
+}
+
+// unwhiteout removes the whiteout file of t and reports if it exists
+func (t *writeTarget) unwhiteout() (ok bool, err error) {
+	if err = os.Remove(t.whiteoutPath()); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return
+	}
+	return true, nil
+}
+
+// opaque creates the opaque file into directory of t
+func (t *writeTarget) opaque() error {
+	return ioutil.WriteFile(filepath.Join(t.realPath, WhiteoutOpaque), nil, 0644)
+}
+
+func (t *writeTarget) whiteoutPath() string {
+	return realWhiteoutOf(t.realPath)
+}
+
+// WriteFile writes data to file pth into the writable layer. See WriteFileC.
+func (fs *AssetFileSystem) WriteFile(pth string, data []byte, perm os.FileMode) error {
+	return fs.WriteFileC(context.Background(), pth, data, perm)
+}
+
+// WriteFileC writes data to file pth into the writable local source of ctx or into the writable layer.
+// Missing parent directories are copied up. If pth is provided by other layer, the file is created with
+// its permissions, otherwise with perm. If pth is provided by a local source with higher priority than the
+// write target, the write would be hidden and returns READ_ONLY_ERROR.
+func (fs *AssetFileSystem) WriteFileC(ctx context.Context, pth string, data []byte, perm os.FileMode) (err error) {
+	var t *writeTarget
+	if t, err = fs.writeTarget(ctx, pth); err != nil {
+		return
+	}
+	defer t.resetWhiteouts()
+	if err = fs.checkOutranked(ctx, t); err != nil {
+		return
+	}
+	if info, err := fs.AssetInfoC(ctx, t.pth); err == nil {
+		if info.Type().IsNameSpace() {
+			return IS_NS_ERROR
+		}
+		if info.IsDir() {
+			return IS_DIR_ERROR
+		}
+		perm = info.Mode().Perm()
+	} else if !isLayerSkipError(err) {
+		return err
+	}
+	if err = fs.mkdirAll(ctx, path.Dir(t.pth), DefaultDirPerm); err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(t.realPath, data, perm); err != nil {
+		return
+	}
+	_, err = t.unwhiteout()
+	return
+}
+
+// MkdirAll creates the directory pth and all parents into the writable layer. See MkdirAllC.
+func (fs *AssetFileSystem) MkdirAll(pth string, perm os.FileMode) (assetfsapi.FileInfo, error) {
+	return fs.MkdirAllC(context.Background(), pth, perm)
+}
+
+// MkdirAllC creates the directory pth and all parents into the writable local source of ctx or into the
+// writable layer, and returns the info of pth. Directories provided by other layers are copied up with
+// its permissions, the others are created with perm.
+func (fs *AssetFileSystem) MkdirAllC(ctx context.Context, pth string, perm os.FileMode) (info assetfsapi.FileInfo, err error) {
+	pth = cleanPath(pth)
+	if err = fs.mkdirAll(ctx, pth, perm); err != nil {
+		return
+	}
+	return fs.AssetInfoC(ctx, pth)
+}
+
+// mkdirAll creates the directory dir and all parents into the write target. The root directory of fs is
+// created too, because it is a subdirectory of the writable layer of parent.
+func (fs *AssetFileSystem) mkdirAll(ctx context.Context, dir string, perm os.FileMode) (err error) {
+	paths := []string{"."}
+	if dir = cleanPath(dir); dir != "." {
+		parts := strings.Split(dir, "/")
+		for i := range parts {
+			paths = append(paths, strings.Join(parts[:i+1], "/"))
+		}
+	}
+	for _, pth := range paths {
+		var t *writeTarget
+		if t, err = fs.writeTarget(ctx, pth); err != nil {
+			return
+		}
+		if info, err := os.Stat(t.realPath); err == nil {
+			if !info.IsDir() {
+				return IS_NOT_DIR_ERROR
+			}
+			continue
+		}
+		p := perm
+		if pth == "." {
+			p = DefaultDirPerm
+		} else if info, err := fs.AssetInfoC(ctx, pth); err == nil {
+			if !info.IsDir() {
+				return IS_NOT_DIR_ERROR
+			}
+			if info.Mode().Perm() != 0 {
+				p = info.Mode().Perm()
+			}
+		} else if !isLayerSkipError(err) {
+			return err
+		}
+		if err = os.MkdirAll(filepath.Dir(t.realPath), DefaultDirPerm); err != nil {
+			return
+		}
+		if err = os.Mkdir(t.realPath, p); err != nil {
+			if os.IsExist(err) {
+				continue
+			}
+			return
+		}
+		defer t.resetWhiteouts()
+		// the directory replaces a removed one: contents of other layers keeps hidden
+		var whitedOut bool
+		if whitedOut, err = t.unwhiteout(); err != nil {
+			return
+		} else if whitedOut {
+			if err = t.opaque(); err != nil {
+				return
+			}
+		}
+	}
+	return nil
+}
+
+// CopyUp copies pth into the writable layer. See CopyUpC.
+func (fs *AssetFileSystem) CopyUp(pth string) (assetfsapi.FileInfo, error) {
+	return fs.CopyUpC(context.Background(), pth)
+}
+
+// CopyUpC copies the file or directory pth provided by other layer into the writable local source of ctx
+// or into the writable layer, if not already there, and returns the info of the copy. Its Writer and
+// Appender does not change the other layers. The contents of directories are not copied.
+func (fs *AssetFileSystem) CopyUpC(ctx context.Context, pth string) (info assetfsapi.FileInfo, err error) {
+	var t *writeTarget
+	if t, err = fs.writeTarget(ctx, pth); err != nil {
+		return
+	}
+	if rinfo, err := os.Stat(t.realPath); err == nil {
+		return newRealFileInfo(t.pth, t.realPath, rinfo), nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if info, err = fs.AssetInfoC(ctx, t.pth); err != nil {
+		return
+	}
+	if info.IsDir() {
+		if err = fs.mkdirAll(ctx, t.pth, DefaultDirPerm); err != nil {
+			return
+		}
+	} else {
+		if err = fs.mkdirAll(ctx, path.Dir(t.pth), DefaultDirPerm); err != nil {
+			return
+		}
+		if err = copyUpFile(info, t.realPath); err != nil {
+			return
+		}
+	}
+	rinfo, err := os.Stat(t.realPath)
+	if err != nil {
+		return nil, err
+	}
+	return newRealFileInfo(t.pth, t.realPath, rinfo), nil
+}
+
+// copyUpFile copies the contents, permissions and modification time of info to file realPath
+func copyUpFile(info assetfsapi.FileInfo, realPath string) (err error) {
+	var data []byte
+	if data, err = Data(info); err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(realPath, data, info.Mode().Perm()); err != nil {
+		return
+	}
+	if !info.ModTime().IsZero() {
+		return os.Chtimes(realPath, info.ModTime(), info.ModTime())
+	}
+	return
+}
+
+// Remove removes pth from the writable layer. See RemoveC.
+func (fs *AssetFileSystem) Remove(pth string) error {
+	return fs.RemoveC(context.Background(), pth)
+}
+
+// RemoveC removes the file or directory pth and all its contents from the writable local source of ctx
+// or from the writable layer. If pth is provided by other layers, a whiteout file is created to hide it.
+// Namespaces can't be removed.
+func (fs *AssetFileSystem) RemoveC(ctx context.Context, pth string) (err error) {
+	var (
+		t     *writeTarget
+		lower bool
+	)
+	if t, _, lower, err = fs.removable(ctx, pth); err != nil {
+		return
+	}
+	defer t.resetWhiteouts()
+	if err = os.RemoveAll(t.realPath); err != nil || !lower {
+		return
+	}
+	if err = fs.mkdirAll(ctx, path.Dir(t.pth), DefaultDirPerm); err != nil {
+		return
+	}
+	return t.whiteout()
+}
+
+// Rename renames oldPath to newPath into the writable layer. See RenameC.
+func (fs *AssetFileSystem) Rename(oldPath, newPath string) error {
+	return fs.RenameC(context.Background(), oldPath, newPath)
+}
+
+// RenameC renames the file or directory oldPath to newPath into the writable local source of ctx or
+// into the writable layer. Missing parent directories of newPath are copied up. A file provided by other
+// layers is copied up before and hidden by a whiteout after. Directories provided by other layers can't be
+// renamed. Paths provided by local sources with higher priority than the write target returns
+// READ_ONLY_ERROR.
+func (fs *AssetFileSystem) RenameC(ctx context.Context, oldPath, newPath string) (err error) {
+	var (
+		src, dst *writeTarget
+		info     assetfsapi.FileInfo
+		lower    bool
+	)
+	if src, info, lower, err = fs.removable(ctx, oldPath); err != nil {
+		return
+	}
+	defer src.resetWhiteouts()
+	if dst, err = fs.writeTarget(ctx, newPath); err != nil {
+		return
+	}
+	defer dst.resetWhiteouts()
+	if dst.pth == "." {
+		return IS_NS_ERROR
+	}
+	if err = fs.checkOutranked(ctx, dst); err != nil {
+		return
+	}
+	if info, err := fs.AssetInfoC(ctx, dst.pth); err == nil && info.Type().IsNameSpace() {
+		return IS_NS_ERROR
+	}
+	if lower {
+		if info.IsDir() {
+			return READ_ONLY_ERROR
+		}
+		if _, err = fs.CopyUpC(ctx, src.pth); err != nil {
+			return
+		}
+	}
+	if err = fs.mkdirAll(ctx, path.Dir(dst.pth), DefaultDirPerm); err != nil {
+		return
+	}
+	if err = os.Rename(src.realPath, dst.realPath); err != nil {
+		return
+	}
+	var whitedOut bool
+	if whitedOut, err = dst.unwhiteout(); err != nil {
+		return
+	}
+	if whitedOut && info.IsDir() {
+		if err = dst.opaque(); err != nil {
+			return
+		}
+	}
+	if lower {
+		return src.whiteout()
+	}
+	return
+}